@@ -51,7 +51,11 @@ func testWrappers() {
 	// ATTENTION: Some logs may NOT be output in asynchronous mode if os.Exit
 	// is called, panicking without recovery and so on.
 	async := writer.NewAsync(writer.Wrap(os.Stderr, nil), 1024)
-	// Close waits until all logs in the channel have been output.
+	// Logs are output in the order in which they are written.
+	// Flush waits until all logs written before it have been output.
+	// Close waits until all logs in the channel have been output, and logs
+	// written after Close are reported with writer.ErrClosed. CloseContext
+	// abandons the rest of the logs when the context is done.
 	// It does NOT close the underlying writer.
 	// To ignore all logs that have not been output, use Abort instead.
 	defer async.Close()
@@ -0,0 +1,217 @@
+// Package admin implements an http.Handler which exposes the levels, flags and
+// slot levels of a Logger as json and allows them to be changed at runtime.
+//
+// The handler has NO authentication. Do NOT expose it to untrusted networks,
+// serve it on a local or an internal address only.
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gxlog/gxlog/iface"
+	"github.com/gxlog/gxlog/logger"
+)
+
+// maxBodySize is the max size of the body of a PUT request.
+const maxBodySize = 64 * 1024
+
+// A State is the runtime state of a Logger. Levels are represented by their
+// names, e.g. "Trace", and the Disabled flags by their names joined by '|',
+// e.g. "Prefix|Runtime".
+type State struct {
+	Level       iface.Level
+	TrackLevel  iface.Level
+	ExitLevel   iface.Level
+	TimingLevel iface.Level
+	PanicLevel  iface.Level
+	Disabled    logger.Flag
+	Slots       []SlotState
+}
+
+// A SlotState is the runtime state of a slot of a Logger.
+type SlotState struct {
+	Slot  logger.Slot
+	Name  string `json:",omitempty"`
+	Level iface.Level
+}
+
+// An update is the body of a PUT request. Any field that is omitted is left
+// to be unchanged. A slot is specified either by its Slot or by its Name.
+type update struct {
+	Level       *iface.Level
+	TrackLevel  *iface.Level
+	ExitLevel   *iface.Level
+	TimingLevel *iface.Level
+	PanicLevel  *iface.Level
+	Disabled    *logger.Flag
+	Slots       []slotUpdate
+}
+
+type slotUpdate struct {
+	Slot  *logger.Slot
+	Name  string
+	Level *iface.Level
+}
+
+// A Handler implements the interface http.Handler.
+//
+// GET responds with the State of the Logger.
+// PUT changes the Logger according to the json in the request body, which has
+// the same structure as State and may omit any field, and then responds with
+// the new State of the Logger. If the json is invalid, NO change is made and
+// it responds with the status 400.
+//
+// All methods of a Handler are concurrency safe.
+// A Handler MUST be created with NewHandler.
+type Handler struct {
+	log *logger.Logger
+}
+
+// NewHandler creates a new Handler of the log. The log must NOT be nil.
+func NewHandler(log *logger.Logger) *Handler {
+	return &Handler{log: log}
+}
+
+// ServeHTTP implements the interface http.Handler.
+func (handler *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		handler.reply(w)
+	case http.MethodPut:
+		if err := handler.update(w, req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		handler.reply(w)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// State returns the State of the Logger.
+func (handler *Handler) State() State {
+	config := handler.log.Config()
+	state := State{
+		Level:       config.Level,
+		TrackLevel:  config.TrackLevel,
+		ExitLevel:   config.ExitLevel,
+		TimingLevel: config.TimingLevel,
+		PanicLevel:  config.PanicLevel,
+		Disabled:    config.Disabled,
+	}
+	count := handler.log.SlotCount()
+	for slot := logger.Slot(0); int(slot) < count; slot++ {
+		state.Slots = append(state.Slots, SlotState{
+			Slot:  slot,
+			Name:  handler.log.SlotName(slot),
+			Level: handler.log.SlotLevel(slot),
+		})
+	}
+	return state
+}
+
+func (handler *Handler) reply(w http.ResponseWriter) {
+	bs, err := json.MarshalIndent(handler.State(), "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(bs, '\n'))
+}
+
+func (handler *Handler) update(w http.ResponseWriter, req *http.Request) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+	var upd update
+	if err := decoder.Decode(&upd); err != nil {
+		return fmt.Errorf("invalid json: %v", err)
+	}
+
+	if err := checkUpdate(&upd); err != nil {
+		return err
+	}
+	slots, err := handler.resolveSlots(upd.Slots)
+	if err != nil {
+		return err
+	}
+
+	handler.log.UpdateConfig(func(config logger.Config) logger.Config {
+		setLevel(&config.Level, upd.Level)
+		setLevel(&config.TrackLevel, upd.TrackLevel)
+		setLevel(&config.ExitLevel, upd.ExitLevel)
+		setLevel(&config.TimingLevel, upd.TimingLevel)
+		setLevel(&config.PanicLevel, upd.PanicLevel)
+		if upd.Disabled != nil {
+			config.Disabled = *upd.Disabled
+		}
+		return config
+	})
+	for i, slot := range slots {
+		handler.log.SetSlotLevel(slot, *upd.Slots[i].Level)
+	}
+	return nil
+}
+
+func (handler *Handler) resolveSlots(upds []slotUpdate) ([]logger.Slot, error) {
+	slots := make([]logger.Slot, 0, len(upds))
+	for _, upd := range upds {
+		if upd.Level == nil {
+			return nil, errors.New("missing Level of slot")
+		}
+		if err := checkLevel("Level of slot", upd.Level, iface.Off); err != nil {
+			return nil, err
+		}
+		var slot logger.Slot
+		switch {
+		case upd.Slot != nil:
+			slot = *upd.Slot
+			if slot < 0 || int(slot) >= handler.log.SlotCount() {
+				return nil, fmt.Errorf("slot %d does not exist", slot)
+			}
+		case upd.Name != "":
+			var ok bool
+			slot, ok = handler.log.NamedSlot(upd.Name)
+			if !ok {
+				return nil, fmt.Errorf("slot %q does not exist", upd.Name)
+			}
+		default:
+			return nil, errors.New("missing Slot or Name of slot")
+		}
+		slots = append(slots, slot)
+	}
+	return slots, nil
+}
+
+func setLevel(dst *iface.Level, src *iface.Level) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+func checkUpdate(upd *update) error {
+	if err := checkLevel("Level", upd.Level, iface.Off); err != nil {
+		return err
+	}
+	if err := checkLevel("TrackLevel", upd.TrackLevel, iface.Off); err != nil {
+		return err
+	}
+	if err := checkLevel("ExitLevel", upd.ExitLevel, iface.Off); err != nil {
+		return err
+	}
+	if err := checkLevel("TimingLevel", upd.TimingLevel, iface.Fatal); err != nil {
+		return err
+	}
+	return checkLevel("PanicLevel", upd.PanicLevel, iface.Fatal)
+}
+
+func checkLevel(name string, level *iface.Level, max iface.Level) error {
+	if level != nil && (*level < iface.Trace || *level > max) {
+		return fmt.Errorf("%s must be between Trace and %v inclusive", name, max)
+	}
+	return nil
+}
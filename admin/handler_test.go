@@ -0,0 +1,110 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gxlog/gxlog/admin"
+	"github.com/gxlog/gxlog/formatter"
+	"github.com/gxlog/gxlog/iface"
+	"github.com/gxlog/gxlog/logger"
+	"github.com/gxlog/gxlog/writer"
+)
+
+func newServer() (*logger.Logger, *httptest.Server) {
+	log := logger.New(logger.Config{Level: iface.Info})
+	log.Link(logger.Slot0, formatter.Null(), writer.Null(), iface.Warn)
+	log.LinkNamed("audit", formatter.Null(), writer.Null())
+	return log, httptest.NewServer(admin.NewHandler(log))
+}
+
+func TestGet(t *testing.T) {
+	_, server := newServer()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("TestGet: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var state map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		t.Fatalf("TestGet: %v", err)
+	}
+	if state["Level"] != "Info" || state["ExitLevel"] != "Off" ||
+		state["Disabled"] != "" {
+		t.Errorf("TestGet: state: %v", state)
+	}
+	slots := state["Slots"].([]interface{})
+	if len(slots) != logger.MaxSlot+1 {
+		t.Fatalf("TestGet: slots: %v", slots)
+	}
+	slot0 := slots[0].(map[string]interface{})
+	audit := slots[logger.MaxSlot].(map[string]interface{})
+	if slot0["Level"] != "Warn" || audit["Name"] != "audit" ||
+		audit["Level"] != "Trace" {
+		t.Errorf("TestGet: slots: %v", slots)
+	}
+}
+
+func TestPut(t *testing.T) {
+	log, server := newServer()
+	defer server.Close()
+
+	body := `{"Level":"Trace","Disabled":"Prefix|Runtime",` +
+		`"Slots":[{"Slot":0,"Level":"Trace"},{"Name":"audit","Level":"Off"}]}`
+	if code := put(t, server.URL, body); code != http.StatusOK {
+		t.Fatalf("TestPut: status: %d", code)
+	}
+	if log.Level() != iface.Trace || log.Disabled() != logger.Prefix|logger.Runtime ||
+		log.SlotLevel(logger.Slot0) != iface.Trace ||
+		log.SlotLevel(logger.MaxSlot) != iface.Off {
+		t.Errorf("TestPut: config: %+v", log.Config())
+	}
+
+	invalids := []string{
+		`{"Level":"Loud"}`,
+		`{"TimingLevel":"Off"}`,
+		`{"Unknown":1}`,
+		`{"Level":"Warn","Slots":[{"Name":"missing","Level":"Trace"}]}`,
+	}
+	for _, body := range invalids {
+		if code := put(t, server.URL, body); code != http.StatusBadRequest {
+			t.Errorf("TestPut: body: %s, status: %d", body, code)
+		}
+	}
+	if log.Level() != iface.Trace {
+		t.Errorf("TestPut: level changed by an invalid request")
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	_, server := newServer()
+	defer server.Close()
+
+	resp, err := http.Post(server.URL, "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("TestMethodNotAllowed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("TestMethodNotAllowed: status: %d", resp.StatusCode)
+	}
+}
+
+func put(t *testing.T, url, body string) int {
+	req, err := http.NewRequest(http.MethodPut, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
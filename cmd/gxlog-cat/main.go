@@ -0,0 +1,121 @@
+// Command gxlog-cat decrypts, decompresses and outputs the log files written
+// by the file writer of gxlog to the standard output.
+//
+// Usage:
+//
+//	gxlog-cat [-key hexkey] [-mode cfb|ctr|ofb|gcm] [-privkey [keyid=]pemfile]... file|dir ...
+//
+// The -privkey flag can be repeated to read log files written with public keys
+// of different key IDs.
+//
+// A directory is read recursively with its log files in time order. A log file
+// truncated by a crash is read up to where it is truncated.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/gxlog/gxlog/writer/file"
+)
+
+var blockModes = map[string]file.BlockCipherMode{
+	"cfb": file.CFB,
+	"ctr": file.CTR,
+	"ofb": file.OFB,
+	"gcm": file.GCM,
+}
+
+func main() {
+	key := flag.String("key", "", "hexadecimal encoded AES key of encrypted log files")
+	mode := flag.String("mode", "cfb", "block mode of AES, cfb, ctr, ofb or gcm")
+	privateKeys := privateKeyFlag{}
+	flag.Var(privateKeys, "privkey",
+		"[keyid=]pemfile of a private key of log files written with a public key")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-key hexkey] "+
+			"[-mode cfb|ctr|ofb|gcm] [-privkey [keyid=]pemfile]... file|dir ...\n",
+			os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	blockMode, ok := blockModes[strings.ToLower(*mode)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "gxlog-cat: unknown block mode: %q\n", *mode)
+		os.Exit(2)
+	}
+
+	config := file.ReaderConfig{
+		AESKey:      *key,
+		BlockMode:   blockMode,
+		PrivateKeys: privateKeys,
+	}
+	failed := false
+	for _, arg := range flag.Args() {
+		pathnames, err := listFiles(arg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "gxlog-cat:", err)
+			failed = true
+			continue
+		}
+		for _, pathname := range pathnames {
+			if err := cat(os.Stdout, pathname, config); err != nil {
+				fmt.Fprintf(os.Stderr, "gxlog-cat: %s: %v\n", pathname, err)
+				failed = true
+			}
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// A privateKeyFlag maps key IDs to PEM encoded private keys.
+type privateKeyFlag map[string]string
+
+func (keys privateKeyFlag) String() string {
+	return ""
+}
+
+func (keys privateKeyFlag) Set(value string) error {
+	keyID, pathname := "", value
+	if i := strings.Index(value, "="); i >= 0 {
+		keyID, pathname = value[:i], value[i+1:]
+	}
+	bs, err := ioutil.ReadFile(pathname)
+	if err != nil {
+		return err
+	}
+	keys[keyID] = string(bs)
+	return nil
+}
+
+func listFiles(pathname string) ([]string, error) {
+	info, err := os.Stat(pathname)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{pathname}, nil
+	}
+	return file.ListFiles(pathname)
+}
+
+func cat(wt io.Writer, pathname string, config file.ReaderConfig) error {
+	reader, err := file.OpenReader(pathname, config)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	_, err = io.Copy(wt, reader)
+	return err
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/gxlog/gxlog/iface"
+	"github.com/gxlog/gxlog/writer"
+)
+
+// An AsyncConfig is used to configure an asynchronous wrapper of a writer.
+// For details of the fields, see writer.AsyncConfig. The notices of logs
+// dropped are formatted by the Formatter of the slot.
+//
+// It can also be a number as the Cap, e.g. {"Async": 1024}.
+// If the Cap is 0, the writer is synchronous.
+type AsyncConfig struct {
+	Cap            int
+	Overflow       OverflowPolicy
+	Timeout        Duration
+	Level          iface.Level
+	NoticeInterval Duration
+	NoNotice       bool
+	ErrorHandler   ErrorHandler
+}
+
+// UnmarshalJSON implements the interface json.Unmarshaler.
+func (config *AsyncConfig) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] != '{' {
+		*config = AsyncConfig{}
+		return json.Unmarshal(data, &config.Cap)
+	}
+	type plain AsyncConfig
+	return decodeStrict(data, (*plain)(config))
+}
+
+func (config *AsyncConfig) check() error {
+	if config.Cap < 0 {
+		return errors.New("Async.Cap must NOT be negative")
+	}
+	if config.Timeout < 0 {
+		return errors.New("Async.Timeout must NOT be negative")
+	}
+	if config.NoticeInterval < 0 {
+		return errors.New("Async.NoticeInterval must NOT be negative")
+	}
+	if err := config.ErrorHandler.check(); err != nil {
+		return err
+	}
+	return checkLevel("Async.Level", config.Level, iface.Off)
+}
+
+func (config *AsyncConfig) config(formatter iface.Formatter) writer.AsyncConfig {
+	return writer.AsyncConfig{
+		Cap:             config.Cap,
+		Overflow:        writer.OverflowPolicy(config.Overflow),
+		Timeout:         time.Duration(config.Timeout),
+		Level:           config.Level,
+		NoticeInterval:  time.Duration(config.NoticeInterval),
+		NoticeFormatter: formatter,
+		NoNotice:        config.NoNotice,
+		ErrorHandler:    config.ErrorHandler.handler(),
+	}
+}
@@ -0,0 +1,201 @@
+// Package config implements a loader that builds a Logger from a declarative
+// json configuration.
+//
+// An example of the configuration:
+//
+//	{
+//	  "Level": "Info",
+//	  "Disabled": "Mark",
+//	  "NameLevels": {"db": "Debug"},
+//	  "Slots": [
+//	    {"Formatter": {"Type": "text", "Coloring": true}, "Writer": {}},
+//	    {
+//	      "Name": "errors",
+//	      "Level": "Error",
+//	      "Formatter": {"Type": "json", "OmitEmpty": "Aux"},
+//	      "Writer": {"Type": "file", "Path": "/var/log/app", "CheckInterval": "10s"},
+//	      "Async": 1024
+//	    }
+//	  ]
+//	}
+//
+// Levels are represented by their names, e.g. "Trace", and bit flags by their
+// names joined by '|', e.g. "Prefix|Runtime". Unknown fields and invalid values
+// are rejected with errors.
+//
+// A Logger built by Build can be reconfigured at runtime with Instance.Apply,
+// and Watch applies a config file each time it is modified or a SIGHUP is
+// received.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gxlog/gxlog/iface"
+	"github.com/gxlog/gxlog/logger"
+)
+
+// A Config is used to build a Logger.
+// The fields that are not specified are left to be the defaults of Logger.
+type Config struct {
+	Level       iface.Level
+	TrackLevel  iface.Level
+	ExitLevel   iface.Level
+	TimingLevel iface.Level
+	PanicLevel  iface.Level
+	Disabled    logger.Flag
+	// NameLevels is the levels of hierarchical names. See Logger.SetNameLevel.
+	NameLevels map[string]iface.Level
+	// ModuleLevels is the spec of module levels. See Logger.SetModuleLevels.
+	ModuleLevels string
+	// Slots are linked in order. A slot without Name is linked to Slot0,
+	// Slot1 and so on in order, while a slot with Name is linked by name after
+	// the predefined slots. There must NOT be more than logger.MaxSlot slots
+	// without Name.
+	Slots []SlotConfig
+}
+
+// A SlotConfig is used to configure a slot of a Logger.
+type SlotConfig struct {
+	// Name is the name of the slot. See Logger.LinkNamed.
+	Name string
+	// Level is the level of the slot. If it is not specified, Trace is used.
+	Level     iface.Level
+	Formatter FormatterConfig
+	Writer    WriterConfig
+	// Async is the config of an asynchronous wrapper of the writer.
+	Async AsyncConfig
+}
+
+// Parse parses the json document from the reader into a Config and checks it.
+func Parse(reader io.Reader) (*Config, error) {
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+	config := &Config{}
+	if err := decoder.Decode(config); err != nil {
+		return nil, fmt.Errorf("config.Parse: %v", err)
+	}
+	if decoder.More() {
+		return nil, errors.New("config.Parse: unexpected data after the document")
+	}
+	if err := config.check(); err != nil {
+		return nil, fmt.Errorf("config.Parse: %v", err)
+	}
+	return config, nil
+}
+
+// Load parses the json document from the reader and builds a Logger with it.
+// The returned io.Closer closes all the writers of the Logger.
+func Load(reader io.Reader) (*logger.Logger, io.Closer, error) {
+	config, err := Parse(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	instance, err := Build(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return instance.Logger(), instance, nil
+}
+
+// LoadFile does the same with Load except that it reads the file.
+func LoadFile(filename string) (*logger.Logger, io.Closer, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("config.LoadFile: %v", err)
+	}
+	defer file.Close()
+
+	return Load(file)
+}
+
+func (config *Config) check() error {
+	if err := checkLevel("Level", config.Level, iface.Off); err != nil {
+		return err
+	}
+	if err := checkLevel("TrackLevel", config.TrackLevel, iface.Off); err != nil {
+		return err
+	}
+	if err := checkLevel("ExitLevel", config.ExitLevel, iface.Off); err != nil {
+		return err
+	}
+	if err := checkLevel("TimingLevel", config.TimingLevel, iface.Fatal); err != nil {
+		return err
+	}
+	if err := checkLevel("PanicLevel", config.PanicLevel, iface.Fatal); err != nil {
+		return err
+	}
+	for name, level := range config.NameLevels {
+		if err := checkLevel(fmt.Sprintf("NameLevels[%q]", name), level,
+			iface.Off); err != nil {
+			return err
+		}
+	}
+	if err := logger.CheckModuleLevels(config.ModuleLevels); err != nil {
+		return err
+	}
+	names := make(map[string]bool)
+	unnamed := 0
+	for i := range config.Slots {
+		slot := &config.Slots[i]
+		if err := slot.check(); err != nil {
+			return fmt.Errorf("Slots[%d]: %v", i, err)
+		}
+		if slot.Name != "" {
+			if names[slot.Name] {
+				return fmt.Errorf("Slots[%d]: duplicate Name %q", i, slot.Name)
+			}
+			names[slot.Name] = true
+		} else if unnamed++; unnamed > logger.MaxSlot {
+			return fmt.Errorf("Slots[%d]: more than %d slots without Name",
+				i, logger.MaxSlot)
+		}
+	}
+	return nil
+}
+
+func (config *Config) loggerConfig() logger.Config {
+	return logger.Config{
+		Level:       config.Level,
+		TrackLevel:  config.TrackLevel,
+		ExitLevel:   config.ExitLevel,
+		TimingLevel: config.TimingLevel,
+		PanicLevel:  config.PanicLevel,
+		Disabled:    config.Disabled,
+	}
+}
+
+func (config *SlotConfig) check() error {
+	if err := checkLevel("Level", config.Level, iface.Off); err != nil {
+		return err
+	}
+	if err := config.Async.check(); err != nil {
+		return err
+	}
+	if err := config.Formatter.check(); err != nil {
+		return fmt.Errorf("Formatter: %v", err)
+	}
+	if err := config.Writer.check(); err != nil {
+		return fmt.Errorf("Writer: %v", err)
+	}
+	return nil
+}
+
+// checkLevel checks the level unless it is not specified.
+func checkLevel(name string, level, max iface.Level) error {
+	if level != 0 && (level < iface.Trace || level > max) {
+		return fmt.Errorf("%s must be between Trace and %v inclusive", name, max)
+	}
+	return nil
+}
+
+func decodeStrict(data []byte, value interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(value)
+}
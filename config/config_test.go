@@ -0,0 +1,210 @@
+package config_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gxlog/gxlog/config"
+	"github.com/gxlog/gxlog/iface"
+	"github.com/gxlog/gxlog/logger"
+)
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gxlog-config")
+	if err != nil {
+		t.Fatalf("TestLoad: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	doc := `{
+		"Level": "Debug",
+		"Disabled": "Mark|Runtime",
+		"NameLevels": {"db": "Trace"},
+		"Slots": [
+			{"Formatter": {"Type": "text", "Header": "{{level}} {{msg}}\n",
+				"ColorMap": {"Warn": "Blue"}}, "Writer": {"Type": "null"},
+				"Async": {"Cap": 8, "Overflow": "DropBelow", "Level": "Warn",
+					"NoticeInterval": "1m"}},
+			{
+				"Name": "errors",
+				"Level": "Error",
+				"Formatter": {"Type": "json", "Omit": "Time|File", "OmitEmpty": "Aux"},
+				"Writer": {"Type": "file", "Path": "` + filepath.ToSlash(dir) + `",
+					"Base": "test", "NoDirForDays": true, "CheckInterval": "10s",
+					"DirPerm": "0750", "Location": "UTC", "RotateEvery": "1h",
+					"RotateAt": ["06:30"]},
+				"Async": 16
+			}
+		]
+	}`
+	log, closer, err := config.Load(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("TestLoad: %v", err)
+	}
+	if log.Level() != iface.Debug || log.Disabled() != logger.Mark|logger.Runtime {
+		t.Errorf("TestLoad: config: %+v", log.Config())
+	}
+	slot, ok := log.NamedSlot("errors")
+	if !ok || log.SlotLevel(slot) != iface.Error {
+		t.Errorf("TestLoad: slot %q is not linked", "errors")
+	}
+	log.Error("to file")
+	if err := closer.Close(); err != nil {
+		t.Errorf("TestLoad: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "test.*.log"))
+	if len(files) != 1 {
+		t.Fatalf("TestLoad: files: %v", files)
+	}
+	bs, _ := ioutil.ReadFile(files[0])
+	if !strings.Contains(string(bs), `"Msg":"to file"`) {
+		t.Errorf("TestLoad: file content: %s", bs)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	docs := []string{
+		`{"Unknown": 1}`,
+		`{"Level": "Loud"}`,
+		`{"TimingLevel": "Off"}`,
+		`{"Disabled": "Color"}`,
+		`{"ModuleLevels": "[=Trace"}`,
+		`{"Slots": [{"Formatter": {"Type": "xml"}}]}`,
+		`{"Slots": [{"Formatter": {"Type": "text", "Omit": "Time"}}]}`,
+		`{"Slots": [{"Writer": {"Type": "file", "DateStyle": "Slash"}}]}`,
+		`{"Slots": [{"Writer": {"Type": "file", "CheckInterval": "5"}}]}`,
+		`{"Slots": [{"Writer": {"Type": "file", "RotateAt": ["25:00"]}}]}`,
+		`{"Slots": [{"Writer": {"Type": "file", "Location": "Mars/Olympus"}}]}`,
+		`{"Slots": [{"Writer": {"Type": "stdout", "Path": "."}}]}`,
+		`{"Slots": [{"Writer": {"Type": "syslog", "Facility": "local9"}}]}`,
+		`{"Slots": [{"Async": -1}]}`,
+		`{"Slots": [{"Async": {"Cap": 8, "Overflow": "DropAll"}}]}`,
+		`{"Slots": [{"Name": "a"}, {"Name": "a"}]}`,
+		`{} {}`,
+	}
+	for _, doc := range docs {
+		if _, err := config.Parse(strings.NewReader(doc)); err == nil {
+			t.Errorf("TestInvalid: expect an error with %s", doc)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gxlog-config")
+	if err != nil {
+		t.Fatalf("TestApply: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	doc := `{"Level": "Info", "Slots": [{"Formatter": {"Header": "{{msg}}\n"},
+		"Writer": {"Type": "file", "Path": "` + filepath.ToSlash(dir) + `",
+		"Base": "%s", "NoDirForDays": true}}]}`
+	instance, err := config.Build(mustParse(t, fmt.Sprintf(doc, "first")))
+	if err != nil {
+		t.Fatalf("TestApply: %v", err)
+	}
+	log := instance.Logger()
+	log.Info("first")
+	writer := log.SlotWriter(logger.Slot0)
+
+	if err := instance.Apply(mustParse(t, fmt.Sprintf(doc, "second"))); err != nil {
+		t.Fatalf("TestApply: %v", err)
+	}
+	if log.SlotWriter(logger.Slot0) != writer {
+		t.Errorf("TestApply: the file writer is NOT reused")
+	}
+	log.Info("second")
+
+	// the regular file makes the unix domain socket writer fail to open
+	socket := filepath.Join(dir, "socket")
+	ioutil.WriteFile(socket, nil, 0600)
+	invalid := mustParse(t, `{"Level": "Error", "Slots": [{"Writer": {"Type": "unix",
+		"Pathname": "`+filepath.ToSlash(socket)+`", "NoOverwrite": true}}]}`)
+	if err := instance.Apply(invalid); err == nil {
+		t.Errorf("TestApply: expect an error")
+	}
+	if log.Level() != iface.Info || log.SlotWriter(logger.Slot0) != writer {
+		t.Errorf("TestApply: the Logger is changed by an invalid config")
+	}
+	if err := instance.Close(); err != nil {
+		t.Errorf("TestApply: %v", err)
+	}
+
+	for _, base := range []string{"first", "second"} {
+		files, _ := filepath.Glob(filepath.Join(dir, base+".*.log"))
+		if len(files) != 1 {
+			t.Fatalf("TestApply: files of %s: %v", base, files)
+		}
+		bs, _ := ioutil.ReadFile(files[0])
+		if string(bs) != base+"\n" {
+			t.Errorf("TestApply: content of %s: %q", base, bs)
+		}
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gxlog-config")
+	if err != nil {
+		t.Fatalf("TestWatcher: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "log.json")
+	ioutil.WriteFile(filename, []byte(`{"Level": "Info"}`), 0600)
+	instance, err := config.Build(mustParse(t, `{"Level": "Info"}`))
+	if err != nil {
+		t.Fatalf("TestWatcher: %v", err)
+	}
+	defer instance.Close()
+
+	errs := make(chan error, 16)
+	watcher, err := config.Watch(instance, filename, config.WatcherConfig{
+		Interval:     time.Millisecond * 10,
+		NoSignal:     true,
+		ErrorHandler: func(err error) { errs <- err },
+	})
+	if err != nil {
+		t.Fatalf("TestWatcher: %v", err)
+	}
+	defer watcher.Close()
+
+	log := instance.Logger()
+	ioutil.WriteFile(filename, []byte(`{"Level": "Warn"}`), 0600)
+	for i := 0; log.Level() != iface.Warn; i++ {
+		if i == 100 {
+			t.Fatalf("TestWatcher: the modification is NOT applied")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	ioutil.WriteFile(filename, []byte(`{"Level": "Loud"}`), 0600)
+	select {
+	case <-errs:
+	case <-time.After(time.Second):
+		t.Fatalf("TestWatcher: the invalid config is NOT reported")
+	}
+	if log.Level() != iface.Warn {
+		t.Errorf("TestWatcher: the Logger is changed by an invalid config")
+	}
+
+	ioutil.WriteFile(filename, []byte(`{"Level": "Error"}`), 0600)
+	if err := watcher.Reload(); err != nil || log.Level() != iface.Error {
+		t.Errorf("TestWatcher: Reload: %v", err)
+	}
+	// Close is called again by the defer
+	watcher.Close()
+}
+
+func mustParse(t *testing.T, doc string) *config.Config {
+	config, err := config.Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("%s: %v", t.Name(), err)
+	}
+	return config
+}
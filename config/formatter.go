@@ -0,0 +1,136 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	jsonfmt "github.com/gxlog/gxlog/formatter/json"
+	"github.com/gxlog/gxlog/formatter/text"
+	"github.com/gxlog/gxlog/iface"
+)
+
+// A FormatterConfig is used to configure the formatter of a slot.
+// In json, the fields of the config of the Type are at the same level as
+// the Type, e.g. {"Type": "text", "Header": "{{level}} {{msg}}\n"}.
+type FormatterConfig struct {
+	// Type is either "text" or "json". If it is not specified, "text" is used.
+	Type string
+	// Text is used when the Type is "text".
+	Text TextConfig
+	// JSON is used when the Type is "json".
+	JSON JSONConfig
+}
+
+// A TextConfig is used to configure a text formatter.
+// For details of the fields, see text.Config.
+type TextConfig struct {
+	Header     string
+	MinBufSize int
+	Coloring   bool
+	ColorMap   map[iface.Level]Color
+}
+
+// A JSONConfig is used to configure a json formatter.
+// For details of the fields, see json.Config.
+type JSONConfig struct {
+	FileSegs   int
+	PkgSegs    int
+	FuncSegs   int
+	Omit       OmitBits
+	OmitEmpty  OmitBits
+	MinBufSize int
+}
+
+// UnmarshalJSON implements the interface json.Unmarshaler.
+// Unknown fields of the Type are rejected.
+func (config *FormatterConfig) UnmarshalJSON(data []byte) error {
+	typ, err := probeType(data)
+	if err != nil {
+		return err
+	}
+	config.Type = typ
+	var fields interface{}
+	switch typ {
+	case "", "text":
+		config.Type = "text"
+		fields = &struct {
+			Type string
+			*TextConfig
+		}{TextConfig: &config.Text}
+	case "json":
+		fields = &struct {
+			Type string
+			*JSONConfig
+		}{JSONConfig: &config.JSON}
+	default:
+		return fmt.Errorf("unknown formatter type: %q", typ)
+	}
+	if err := decodeStrict(data, fields); err != nil {
+		return fmt.Errorf("%s formatter: %v", config.Type, err)
+	}
+	return nil
+}
+
+func (config *FormatterConfig) check() error {
+	switch config.Type {
+	case "", "text":
+		if config.Text.MinBufSize < 0 {
+			return errors.New("MinBufSize must NOT be negative")
+		}
+		for level := range config.Text.ColorMap {
+			if level < iface.Trace || level > iface.Fatal {
+				return fmt.Errorf("invalid level in ColorMap: %v", level)
+			}
+		}
+	case "json":
+		if config.JSON.MinBufSize < 0 {
+			return errors.New("MinBufSize must NOT be negative")
+		}
+	default:
+		return fmt.Errorf("unknown formatter type: %q", config.Type)
+	}
+	return nil
+}
+
+func (config *FormatterConfig) build() iface.Formatter {
+	if config.Type == "json" {
+		return jsonfmt.New(config.JSON.config())
+	}
+	return text.New(config.Text.config())
+}
+
+func (config *TextConfig) config() text.Config {
+	colorMap := make(map[iface.Level]text.Color, len(config.ColorMap))
+	for level, color := range config.ColorMap {
+		colorMap[level] = text.Color(color)
+	}
+	return text.Config{
+		Header:     config.Header,
+		MinBufSize: config.MinBufSize,
+		ColorMap:   colorMap,
+		Coloring:   config.Coloring,
+	}
+}
+
+func (config *JSONConfig) config() jsonfmt.Config {
+	return jsonfmt.Config{
+		FileSegs:   config.FileSegs,
+		PkgSegs:    config.PkgSegs,
+		FuncSegs:   config.FuncSegs,
+		Omit:       jsonfmt.OmitBits(config.Omit),
+		OmitEmpty:  jsonfmt.OmitBits(config.OmitEmpty),
+		MinBufSize: config.MinBufSize,
+	}
+}
+
+func probeType(data []byte) (string, error) {
+	var probe struct {
+		Type string
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return "", err
+	}
+	return strings.ToLower(probe.Type), nil
+}
@@ -0,0 +1,368 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/gxlog/gxlog/iface"
+	"github.com/gxlog/gxlog/logger"
+	"github.com/gxlog/gxlog/writer"
+	"github.com/gxlog/gxlog/writer/file"
+)
+
+// An Instance is a Logger built from a Config together with the formatters
+// and writers of its slots.
+//
+// All methods of an Instance are concurrency safe.
+// An Instance MUST be created with Build.
+type Instance struct {
+	log    *logger.Logger
+	slots  []*slotInstance
+	closed bool
+
+	lock sync.Mutex
+}
+
+type slotInstance struct {
+	Config    SlotConfig
+	Slot      logger.Slot
+	Formatter iface.Formatter
+	Writer    iface.Writer
+	Async     *writer.Async
+	// nil if the writer need NOT be closed
+	Closer io.Closer
+}
+
+// Build builds a Logger with the config. The config should have been checked
+// by Parse. If an error occurs, all the writers opened are closed.
+func Build(config *Config) (*Instance, error) {
+	log := logger.New(config.loggerConfig())
+	log.SetNameLevels(config.NameLevels)
+	if err := log.SetModuleLevels(config.ModuleLevels); err != nil {
+		return nil, fmt.Errorf("config.Build: %v", err)
+	}
+
+	instance := &Instance{log: log}
+	next := logger.Slot0
+	for i := range config.Slots {
+		slot, err := buildSlot(&config.Slots[i])
+		if err != nil {
+			instance.Close()
+			return nil, fmt.Errorf("config.Build: Slots[%d]: %v", i, err)
+		}
+		if slot.Config.Name == "" {
+			slot.Slot = next
+			next++
+		}
+		instance.link(slot)
+		instance.slots = append(instance.slots, slot)
+	}
+	return instance, nil
+}
+
+// Logger returns the Logger of the Instance.
+func (instance *Instance) Logger() *logger.Logger {
+	return instance.log
+}
+
+// Apply applies the config to the Logger of the Instance without dropping
+// any log. The levels and flags of the Logger are replaced. A slot is matched
+// with the slot of the same Name, or of the same index among the slots without
+// Name. The formatter of a slot is rebuilt only if its config is changed.
+// The file writer of a slot is reconfigured with SetConfig if it is still a
+// file writer, otherwise the writer is reused if its config is NOT changed or
+// replaced with a newly opened one. Writers no longer used are closed after
+// the slots have been relinked.
+//
+// If the config is invalid or any writer fails to open or reconfigure, it
+// returns an error and the Logger is left to be unchanged. An error that occurs
+// when closing the writers no longer used is returned after the config has
+// been applied.
+func (instance *Instance) Apply(config *Config) error {
+	if err := config.check(); err != nil {
+		return fmt.Errorf("config.Apply: %v", err)
+	}
+
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+
+	if instance.closed {
+		return errors.New("config.Apply: the Instance is closed")
+	}
+	plans, err := instance.prepare(config)
+	if err != nil {
+		return fmt.Errorf("config.Apply: %v", err)
+	}
+	if err := instance.commit(config, plans); err != nil {
+		return fmt.Errorf("config.Apply: %v", err)
+	}
+	return nil
+}
+
+// Close unlinks all the slots of the Logger, waits until all the logs in the
+// asynchronous wrappers have been output and then closes all the writers.
+// It returns the first error that occurs.
+func (instance *Instance) Close() error {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+
+	instance.closed = true
+	var firstErr error
+	for _, slot := range instance.slots {
+		instance.log.Unlink(slot.Slot)
+		if err := slot.close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	instance.slots = nil
+	if firstErr != nil {
+		return fmt.Errorf("config.Close: %v", firstErr)
+	}
+	return nil
+}
+
+// A slotPlan is a slot of the config to apply, prepared without any side
+// effect on the Logger.
+type slotPlan struct {
+	// nil if there is no matched slot
+	Old  *slotInstance
+	Slot *slotInstance
+	// not nil if the file writer of the Old is to be reconfigured
+	FileConfig *file.Config
+	// not nil after the file writer of the Old has been reconfigured
+	OldFileConfig *file.Config
+	// whether the writer is newly opened
+	Opened bool
+}
+
+func (instance *Instance) prepare(config *Config) ([]slotPlan, error) {
+	named := make(map[string]*slotInstance)
+	var unnamed []*slotInstance
+	for _, slot := range instance.slots {
+		if slot.Config.Name != "" {
+			named[slot.Config.Name] = slot
+		} else {
+			unnamed = append(unnamed, slot)
+		}
+	}
+
+	plans := make([]slotPlan, 0, len(config.Slots))
+	for i := range config.Slots {
+		slotConfig := &config.Slots[i]
+		var old *slotInstance
+		if slotConfig.Name != "" {
+			old = named[slotConfig.Name]
+		} else if n := countUnnamed(config.Slots[:i]); n < len(unnamed) {
+			old = unnamed[n]
+		}
+		plan, err := prepareSlot(slotConfig, old)
+		if err != nil {
+			closeOpened(plans)
+			return nil, fmt.Errorf("Slots[%d]: %v", i, err)
+		}
+		plans = append(plans, plan)
+	}
+	return plans, nil
+}
+
+func (instance *Instance) commit(config *Config, plans []slotPlan) error {
+	// the file writers are reconfigured first, so that the Logger is left to
+	// be unchanged if any of them fails
+	if err := reconfigure(plans); err != nil {
+		closeOpened(plans)
+		return err
+	}
+	log := instance.log
+	if err := log.SetModuleLevels(config.ModuleLevels); err != nil {
+		rollback(plans)
+		closeOpened(plans)
+		return err
+	}
+	loggerConfig := config.loggerConfig()
+	loggerConfig.Filter = log.Config().Filter
+	log.SetConfig(loggerConfig)
+	log.SetNameLevels(config.NameLevels)
+
+	var firstErr error
+	slots := make([]*slotInstance, 0, len(plans))
+	inUse := make(map[interface{}]bool)
+	next := logger.Slot0
+	for i := range plans {
+		slot := plans[i].Slot
+		if slot.Config.Name == "" {
+			slot.Slot = next
+			next++
+		}
+		instance.link(slot)
+		slots = append(slots, slot)
+		inUse[slot.Slot] = true
+		inUse[slot.Async] = true
+		inUse[slot.Closer] = true
+	}
+
+	for _, old := range instance.slots {
+		if !inUse[old.Slot] {
+			log.Unlink(old.Slot)
+		}
+		if old.Async != nil && !inUse[old.Async] {
+			old.Async.Close()
+		}
+		if old.Closer != nil && !inUse[old.Closer] {
+			if err := old.Closer.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	instance.slots = slots
+	return firstErr
+}
+
+// reconfigure sets the configs to the file writers of the plans. If any of
+// them fails, the file writers reconfigured are rolled back.
+func reconfigure(plans []slotPlan) error {
+	for i := range plans {
+		plan := &plans[i]
+		if plan.FileConfig == nil {
+			continue
+		}
+		wt := plan.Old.Writer.(*file.Writer)
+		oldConfig := wt.Config()
+		if err := wt.SetConfig(*plan.FileConfig); err != nil {
+			rollback(plans[:i])
+			return fmt.Errorf("Slots[%d]: %v", i, err)
+		}
+		plan.OldFileConfig = &oldConfig
+	}
+	return nil
+}
+
+// rollback sets the old configs back to the file writers reconfigured.
+func rollback(plans []slotPlan) {
+	for _, plan := range plans {
+		if plan.OldFileConfig != nil {
+			// the old config has been set successfully
+			plan.Old.Writer.(*file.Writer).SetConfig(*plan.OldFileConfig)
+		}
+	}
+}
+
+// link links the slot and creates the asynchronous wrapper if necessary.
+func (instance *Instance) link(slot *slotInstance) {
+	if slot.Async == nil && slot.Config.Async.Cap > 0 {
+		// the config has been checked
+		slot.Async, _ = writer.NewAsyncWithConfig(slot.Writer,
+			slot.Config.Async.config(slot.Formatter))
+	}
+	if slot.Config.Name != "" {
+		slot.Slot = instance.log.LinkNamed(slot.Config.Name, slot.Formatter,
+			slot.writer(), slot.Config.level())
+	} else {
+		instance.log.Link(slot.Slot, slot.Formatter, slot.writer(),
+			slot.Config.level())
+	}
+}
+
+func prepareSlot(config *SlotConfig, old *slotInstance) (slotPlan, error) {
+	if old == nil {
+		slot, err := buildSlot(config)
+		return slotPlan{Slot: slot, Opened: true}, err
+	}
+
+	plan := slotPlan{
+		Old: old,
+		Slot: &slotInstance{
+			Config:    *config,
+			Formatter: old.Formatter,
+			Writer:    old.Writer,
+			Closer:    old.Closer,
+		},
+	}
+	if !reflect.DeepEqual(config.Formatter, old.Config.Formatter) {
+		plan.Slot.Formatter = config.Formatter.build()
+	}
+	if !reflect.DeepEqual(config.Writer, old.Config.Writer) {
+		if config.Writer.Type == "file" && old.Config.Writer.Type == "file" {
+			fileConfig := config.Writer.File.config()
+			if err := file.CheckConfig(fileConfig); err != nil {
+				return slotPlan{}, err
+			}
+			plan.FileConfig = &fileConfig
+		} else {
+			wt, closer, err := config.Writer.open()
+			if err != nil {
+				return slotPlan{}, err
+			}
+			plan.Slot.Writer = wt
+			plan.Slot.Closer = closer
+			plan.Opened = true
+		}
+	}
+	// the Async formats notices with the Formatter
+	if !plan.Opened && config.Async == old.Config.Async &&
+		plan.Slot.Formatter == old.Formatter {
+		plan.Slot.Async = old.Async
+	}
+	return plan, nil
+}
+
+func closeOpened(plans []slotPlan) {
+	for _, plan := range plans {
+		if plan.Opened && plan.Slot.Closer != nil {
+			plan.Slot.Closer.Close()
+		}
+	}
+}
+
+func countUnnamed(slots []SlotConfig) int {
+	count := 0
+	for i := range slots {
+		if slots[i].Name == "" {
+			count++
+		}
+	}
+	return count
+}
+
+// buildSlot opens the writer and builds the formatter. The asynchronous
+// wrapper is created when the slot is linked.
+func buildSlot(config *SlotConfig) (*slotInstance, error) {
+	wt, closer, err := config.Writer.open()
+	if err != nil {
+		return nil, err
+	}
+	slot := &slotInstance{
+		Config:    *config,
+		Formatter: config.Formatter.build(),
+		Writer:    wt,
+		Closer:    closer,
+	}
+	return slot, nil
+}
+
+func (config *SlotConfig) level() iface.Level {
+	if config.Level == 0 {
+		return iface.Trace
+	}
+	return config.Level
+}
+
+// writer returns the writer to link.
+func (slot *slotInstance) writer() iface.Writer {
+	if slot.Async != nil {
+		return slot.Async
+	}
+	return slot.Writer
+}
+
+func (slot *slotInstance) close() error {
+	if slot.Async != nil {
+		slot.Async.Close()
+	}
+	if slot.Closer != nil {
+		return slot.Closer.Close()
+	}
+	return nil
+}
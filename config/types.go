@@ -0,0 +1,307 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	jsonfmt "github.com/gxlog/gxlog/formatter/json"
+	"github.com/gxlog/gxlog/formatter/text"
+	"github.com/gxlog/gxlog/writer"
+	"github.com/gxlog/gxlog/writer/file"
+	"github.com/gxlog/gxlog/writer/syslog"
+)
+
+// A Duration is a time.Duration in the format of time.ParseDuration, e.g. "5s".
+type Duration time.Duration
+
+// UnmarshalText implements the interface encoding.TextUnmarshaler.
+func (duration *Duration) UnmarshalText(text []byte) error {
+	value, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*duration = Duration(value)
+	return nil
+}
+
+// A TimeOfDay is a time.Duration since the midnight in the format of "15:04"
+// or "15:04:05", e.g. "06:30".
+type TimeOfDay time.Duration
+
+// UnmarshalText implements the interface encoding.TextUnmarshaler.
+func (offset *TimeOfDay) UnmarshalText(text []byte) error {
+	tm, err := time.Parse("15:04:05", string(text))
+	if err != nil {
+		if tm, err = time.Parse("15:04", string(text)); err != nil {
+			return fmt.Errorf("invalid time of day: %q", text)
+		}
+	}
+	*offset = TimeOfDay(time.Duration(tm.Hour())*time.Hour +
+		time.Duration(tm.Minute())*time.Minute +
+		time.Duration(tm.Second())*time.Second)
+	return nil
+}
+
+// A Location is the name of a time zone loaded by time.LoadLocation,
+// e.g. "UTC", "Local" or "America/New_York".
+type Location string
+
+// UnmarshalText implements the interface encoding.TextUnmarshaler.
+func (location *Location) UnmarshalText(text []byte) error {
+	if _, err := time.LoadLocation(string(text)); err != nil {
+		return err
+	}
+	*location = Location(text)
+	return nil
+}
+
+// location returns the time zone. It returns nil if the Location is empty.
+func (location Location) location() *time.Location {
+	if location == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(string(location))
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+// A FileMode is an os.FileMode of permission bits in octal, e.g. "0700".
+type FileMode os.FileMode
+
+// UnmarshalText implements the interface encoding.TextUnmarshaler.
+func (mode *FileMode) UnmarshalText(text []byte) error {
+	value, err := strconv.ParseUint(string(text), 8, 32)
+	if err != nil || value&^uint64(os.ModePerm) != 0 {
+		return fmt.Errorf("invalid file mode: %q", text)
+	}
+	*mode = FileMode(value)
+	return nil
+}
+
+// A Color is a text.Color by name, e.g. "Red" or "BrightRed".
+type Color text.Color
+
+var colorNames = map[string]int{
+	"black":         int(text.Black),
+	"red":           int(text.Red),
+	"green":         int(text.Green),
+	"yellow":        int(text.Yellow),
+	"blue":          int(text.Blue),
+	"magenta":       int(text.Magenta),
+	"cyan":          int(text.Cyan),
+	"white":         int(text.White),
+	"brightblack":   int(text.BrightBlack),
+	"brightred":     int(text.BrightRed),
+	"brightgreen":   int(text.BrightGreen),
+	"brightyellow":  int(text.BrightYellow),
+	"brightblue":    int(text.BrightBlue),
+	"brightmagenta": int(text.BrightMagenta),
+	"brightcyan":    int(text.BrightCyan),
+	"brightwhite":   int(text.BrightWhite),
+}
+
+// UnmarshalText implements the interface encoding.TextUnmarshaler.
+func (color *Color) UnmarshalText(text []byte) error {
+	value, err := lookupName("color", colorNames, string(text))
+	*color = Color(value)
+	return err
+}
+
+// An OmitBits is a json.OmitBits by the names of fields joined by '|',
+// e.g. "Time|File|Aux".
+type OmitBits jsonfmt.OmitBits
+
+var omitBitsNames = map[string]int{
+	"time":    int(jsonfmt.Time),
+	"level":   int(jsonfmt.Level),
+	"file":    int(jsonfmt.File),
+	"line":    int(jsonfmt.Line),
+	"pkg":     int(jsonfmt.Pkg),
+	"func":    int(jsonfmt.Func),
+	"msg":     int(jsonfmt.Msg),
+	"prefix":  int(jsonfmt.Prefix),
+	"context": int(jsonfmt.Context),
+	"mark":    int(jsonfmt.Mark),
+	"name":    int(jsonfmt.Name),
+	"aux":     int(jsonfmt.Aux),
+}
+
+// UnmarshalText implements the interface encoding.TextUnmarshaler.
+func (bits *OmitBits) UnmarshalText(text []byte) error {
+	var value OmitBits
+	for _, name := range strings.Split(string(text), "|") {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		bit, err := lookupName("field", omitBitsNames, name)
+		if err != nil {
+			return err
+		}
+		value |= OmitBits(bit)
+	}
+	*bits = value
+	return nil
+}
+
+// A DateStyle is a file.DateStyle by name, e.g. "Dash".
+type DateStyle file.DateStyle
+
+var dateStyleNames = map[string]int{
+	"compact":    int(file.DateCompact),
+	"dash":       int(file.DateDash),
+	"underscore": int(file.DateUnderscore),
+	"dot":        int(file.DateDot),
+}
+
+// UnmarshalText implements the interface encoding.TextUnmarshaler.
+func (style *DateStyle) UnmarshalText(text []byte) error {
+	value, err := lookupName("date style", dateStyleNames, string(text))
+	*style = DateStyle(value)
+	return err
+}
+
+// A TimeStyle is a file.TimeStyle by name, e.g. "Colon".
+type TimeStyle file.TimeStyle
+
+var timeStyleNames = map[string]int{
+	"compact":    int(file.TimeCompact),
+	"dash":       int(file.TimeDash),
+	"underscore": int(file.TimeUnderscore),
+	"dot":        int(file.TimeDot),
+	"colon":      int(file.TimeColon),
+	"sequence":   int(file.TimeSequence),
+}
+
+// UnmarshalText implements the interface encoding.TextUnmarshaler.
+func (style *TimeStyle) UnmarshalText(text []byte) error {
+	value, err := lookupName("time style", timeStyleNames, string(text))
+	*style = TimeStyle(value)
+	return err
+}
+
+// A Compression is a file.Compression by name, e.g. "Gzip".
+type Compression file.Compression
+
+var compressionNames = map[string]int{
+	"none":  int(file.CompressNone),
+	"gzip":  int(file.CompressGzip),
+	"zlib":  int(file.CompressZlib),
+	"flate": int(file.CompressFlate),
+}
+
+// UnmarshalText implements the interface encoding.TextUnmarshaler.
+func (compression *Compression) UnmarshalText(text []byte) error {
+	value, err := lookupName("compression", compressionNames, string(text))
+	*compression = Compression(value)
+	return err
+}
+
+// A SpacePolicy is a file.SpacePolicy by name, e.g. "Pause".
+type SpacePolicy file.SpacePolicy
+
+var spacePolicyNames = map[string]int{
+	"drop":      int(file.SpaceDrop),
+	"retention": int(file.SpaceRetention),
+	"pause":     int(file.SpacePause),
+}
+
+// UnmarshalText implements the interface encoding.TextUnmarshaler.
+func (policy *SpacePolicy) UnmarshalText(text []byte) error {
+	value, err := lookupName("space policy", spacePolicyNames, string(text))
+	*policy = SpacePolicy(value)
+	return err
+}
+
+// An OverflowPolicy is a writer.OverflowPolicy by name, e.g. "DropOldest".
+type OverflowPolicy writer.OverflowPolicy
+
+var overflowPolicyNames = map[string]int{
+	"block":      int(writer.OverflowBlock),
+	"timeout":    int(writer.OverflowTimeout),
+	"dropnewest": int(writer.OverflowDropNewest),
+	"dropoldest": int(writer.OverflowDropOldest),
+	"dropbelow":  int(writer.OverflowDropBelow),
+}
+
+// UnmarshalText implements the interface encoding.TextUnmarshaler.
+func (policy *OverflowPolicy) UnmarshalText(text []byte) error {
+	value, err := lookupName("overflow policy", overflowPolicyNames, string(text))
+	*policy = OverflowPolicy(value)
+	return err
+}
+
+// A BlockMode is a file.BlockCipherMode by name, e.g. "CTR".
+type BlockMode file.BlockCipherMode
+
+var blockModeNames = map[string]int{
+	"cfb": int(file.CFB),
+	"ctr": int(file.CTR),
+	"ofb": int(file.OFB),
+	"gcm": int(file.GCM),
+}
+
+// UnmarshalText implements the interface encoding.TextUnmarshaler.
+func (mode *BlockMode) UnmarshalText(text []byte) error {
+	value, err := lookupName("block mode", blockModeNames, string(text))
+	*mode = BlockMode(value)
+	return err
+}
+
+// A Facility is a syslog.Facility by name, e.g. "User".
+type Facility syslog.Facility
+
+var facilityNames = map[string]int{
+	"kern":     int(syslog.FacKern),
+	"user":     int(syslog.FacUser),
+	"mail":     int(syslog.FacMail),
+	"daemon":   int(syslog.FacDaemon),
+	"auth":     int(syslog.FacAuth),
+	"syslog":   int(syslog.FacSyslog),
+	"lpr":      int(syslog.FacLPR),
+	"news":     int(syslog.FacNews),
+	"uucp":     int(syslog.FacUUCP),
+	"cron":     int(syslog.FacCron),
+	"authpriv": int(syslog.FacAuthPriv),
+	"ftp":      int(syslog.FacFTP),
+}
+
+// UnmarshalText implements the interface encoding.TextUnmarshaler.
+func (facility *Facility) UnmarshalText(text []byte) error {
+	value, err := lookupName("facility", facilityNames, string(text))
+	*facility = Facility(value)
+	return err
+}
+
+// A Severity is a syslog.Severity by name, e.g. "Warning".
+type Severity syslog.Severity
+
+var severityNames = map[string]int{
+	"emerg":   int(syslog.SevEmerg),
+	"alert":   int(syslog.SevAlert),
+	"crit":    int(syslog.SevCrit),
+	"err":     int(syslog.SevErr),
+	"warning": int(syslog.SevWarning),
+	"notice":  int(syslog.SevNotice),
+	"info":    int(syslog.SevInfo),
+	"debug":   int(syslog.SevDebug),
+}
+
+// UnmarshalText implements the interface encoding.TextUnmarshaler.
+func (severity *Severity) UnmarshalText(text []byte) error {
+	value, err := lookupName("severity", severityNames, string(text))
+	*severity = Severity(value)
+	return err
+}
+
+func lookupName(kind string, names map[string]int, name string) (int, error) {
+	value, ok := names[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return 0, fmt.Errorf("unknown %s: %q", kind, name)
+	}
+	return value, nil
+}
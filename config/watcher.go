@@ -0,0 +1,202 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// A WatcherConfig is used to configure a Watcher.
+type WatcherConfig struct {
+	// Interval is the time interval to check whether the config file has been
+	// modified.
+	// If Interval is not specified, (time.Second * 5) is used.
+	// It must NOT be negative.
+	Interval time.Duration
+	// Signals are the signals that trigger a reload.
+	// If Signals is not specified, syscall.SIGHUP is used, except on js where
+	// no signal is used.
+	Signals []os.Signal
+	// NoSignal specifies NOT to reload on any signal.
+	NoSignal bool
+	// ErrorHandler will be called when a reload fails. The Logger is left to
+	// be unchanged when a reload fails.
+	// If ErrorHandler is not specified, the error is output by log.Output.
+	ErrorHandler func(err error)
+}
+
+func (config *WatcherConfig) setDefaults() {
+	if config.Interval == 0 {
+		config.Interval = time.Second * 5
+	}
+	if config.Signals == nil {
+		config.Signals = defaultSignals
+	}
+	if config.ErrorHandler == nil {
+		config.ErrorHandler = reportError
+	}
+}
+
+func (config *WatcherConfig) check() error {
+	if config.Interval < 0 {
+		return errors.New("WatcherConfig.Interval must NOT be negative")
+	}
+	return nil
+}
+
+// A Watcher polls a config file and applies it to an Instance each time
+// the file is modified or a signal is received.
+//
+// All methods of a Watcher are concurrency safe.
+// A Watcher MUST be created with Watch.
+type Watcher struct {
+	instance *Instance
+	filename string
+	config   WatcherConfig
+
+	modTime time.Time
+	size    int64
+	content []byte
+	failed  bool
+
+	chanSignal chan os.Signal
+	chanClose  chan struct{}
+	closeOnce  sync.Once
+	done       sync.WaitGroup
+
+	lock sync.Mutex
+}
+
+// Watch creates a new Watcher that watches the config file and applies it to
+// the instance. The instance should have been built with the current content
+// of the file, which is NOT applied again until the file is modified.
+func Watch(instance *Instance, filename string, config WatcherConfig) (
+	*Watcher, error) {
+
+	config.setDefaults()
+	if err := config.check(); err != nil {
+		return nil, fmt.Errorf("config.Watch: %v", err)
+	}
+	watcher := &Watcher{
+		instance:  instance,
+		filename:  filename,
+		config:    config,
+		chanClose: make(chan struct{}),
+	}
+	if info, err := os.Stat(filename); err == nil {
+		watcher.modTime = info.ModTime()
+		watcher.size = info.Size()
+	}
+	watcher.content, _ = ioutil.ReadFile(filename)
+	// signal.Notify relays all signals without any signal specified
+	if !config.NoSignal && len(config.Signals) > 0 {
+		watcher.chanSignal = make(chan os.Signal, 1)
+		signal.Notify(watcher.chanSignal, config.Signals...)
+	}
+	watcher.done.Add(1)
+	go watcher.serve()
+	return watcher, nil
+}
+
+// Reload reads the config file and applies it to the Instance immediately,
+// even if the file has NOT been modified.
+// If the config is invalid, it returns an error and the Logger is left to be
+// unchanged.
+func (watcher *Watcher) Reload() error {
+	watcher.lock.Lock()
+	defer watcher.lock.Unlock()
+
+	if err := watcher.reload(true); err != nil {
+		return fmt.Errorf("config.Reload: %v", err)
+	}
+	return nil
+}
+
+// Close stops watching. It does NOT close the Instance.
+// It is safe to call Close more than once.
+func (watcher *Watcher) Close() {
+	watcher.closeOnce.Do(func() {
+		if watcher.chanSignal != nil {
+			signal.Stop(watcher.chanSignal)
+		}
+		close(watcher.chanClose)
+	})
+	watcher.done.Wait()
+}
+
+func (watcher *Watcher) serve() {
+	defer watcher.done.Done()
+
+	ticker := time.NewTicker(watcher.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			watcher.poll()
+		case <-watcher.chanSignal:
+			watcher.lock.Lock()
+			if err := watcher.reload(true); err != nil {
+				watcher.config.ErrorHandler(fmt.Errorf("config.Watcher: %v", err))
+			}
+			watcher.lock.Unlock()
+		case <-watcher.chanClose:
+			return
+		}
+	}
+}
+
+func (watcher *Watcher) poll() {
+	watcher.lock.Lock()
+	defer watcher.lock.Unlock()
+
+	info, err := os.Stat(watcher.filename)
+	if err != nil {
+		// report only once until the file is back
+		if !watcher.failed {
+			watcher.failed = true
+			watcher.config.ErrorHandler(fmt.Errorf("config.Watcher: %v", err))
+		}
+		return
+	}
+	if info.ModTime().Equal(watcher.modTime) && info.Size() == watcher.size {
+		return
+	}
+	watcher.modTime = info.ModTime()
+	watcher.size = info.Size()
+	if err := watcher.reload(false); err != nil {
+		watcher.config.ErrorHandler(fmt.Errorf("config.Watcher: %v", err))
+	}
+}
+
+// reload applies the config file. If the force is false, the file is NOT
+// applied when its content is the same as the last read one.
+func (watcher *Watcher) reload(force bool) error {
+	content, err := ioutil.ReadFile(watcher.filename)
+	if err != nil {
+		watcher.failed = true
+		return err
+	}
+	watcher.failed = false
+	if !force && bytes.Equal(content, watcher.content) {
+		return nil
+	}
+	// remember the content even if it is invalid, and it will NOT be reported
+	// again until the file is modified
+	watcher.content = content
+	config, err := Parse(bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	return watcher.instance.Apply(config)
+}
+
+func reportError(err error) {
+	log.Output(2, fmt.Sprintln("log error:", err))
+}
@@ -0,0 +1,6 @@
+package config
+
+import "os"
+
+// no signal triggers a reload by default on js
+var defaultSignals []os.Signal
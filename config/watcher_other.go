@@ -0,0 +1,11 @@
+//go:build !js
+
+package config
+
+import (
+	"os"
+	"syscall"
+)
+
+// the signals that trigger a reload by default
+var defaultSignals = []os.Signal{syscall.SIGHUP}
@@ -0,0 +1,311 @@
+package config
+
+import (
+	"compress/flate"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/gxlog/gxlog/iface"
+	"github.com/gxlog/gxlog/writer"
+	"github.com/gxlog/gxlog/writer/file"
+	"github.com/gxlog/gxlog/writer/socket/tcp"
+	"github.com/gxlog/gxlog/writer/socket/unix"
+	"github.com/gxlog/gxlog/writer/syslog"
+)
+
+// A WriterConfig is used to configure the writer of a slot.
+// In json, the fields of the config of the Type are at the same level as
+// the Type, e.g. {"Type": "file", "Path": "/var/log/app"}.
+type WriterConfig struct {
+	// Type is one of "stderr", "stdout", "null", "file", "syslog", "tcp" and
+	// "unix". The writers of "stderr", "stdout" and "null" have no config.
+	// If Type is not specified, "stderr" is used.
+	Type string
+	// File is used when the Type is "file".
+	File FileConfig
+	// Syslog is used when the Type is "syslog".
+	Syslog SyslogConfig
+	// TCP is used when the Type is "tcp".
+	TCP TCPConfig
+	// Unix is used when the Type is "unix".
+	Unix UnixConfig
+}
+
+// An ErrorHandler is the name of a writer.ErrorHandler. It is either empty,
+// "Report" or "ReportDetails".
+type ErrorHandler string
+
+// A FileConfig is used to configure a file writer.
+// For details of the fields, see file.Config, except that Preamble specifies
+// to use file.DefaultPreamble.
+type FileConfig struct {
+	Path           string
+	Filename       string
+	ReopenOnSignal bool
+	Base           string
+	Ext            string
+	Separator      string
+	DateStyle      DateStyle
+	TimeStyle      TimeStyle
+	Location       Location
+	RotateEvery    Duration
+	RotateAt       []TimeOfDay
+	MaxFileSize    int64
+	Append         bool
+	Shared         bool
+	CheckInterval  Duration
+	BufferSize     int
+	FlushInterval  Duration
+	Sync           SyncConfig
+	Compression    Compression
+	CompressLevel  int
+	GzipLevel      int
+	AESKey         string
+	BlockMode      BlockMode
+	PublicKey      string
+	KeyID          string
+	Preamble       bool
+	ErrorHandler   ErrorHandler
+	DirPerm        FileMode
+	NoDirForDays   bool
+	Symlink        string
+	MaxFiles       int
+	MaxAge         Duration
+	MaxTotalSize   int64
+	MinFreeSpace   int64
+	MaxDirUsage    int64
+	SpacePolicy    SpacePolicy
+	SpaceLevel     iface.Level
+}
+
+// A SyncConfig is used to configure the sync policy of a file writer.
+// For details of the fields, see file.SyncPolicy.
+type SyncConfig struct {
+	EveryN   int
+	Interval Duration
+	Level    iface.Level
+}
+
+// A SyslogConfig is used to configure a syslog writer.
+// For details of the fields, see syslog.Config.
+type SyslogConfig struct {
+	Tag          string
+	Facility     Facility
+	Network      string
+	Addr         string
+	SeverityMap  map[iface.Level]Severity
+	ErrorHandler ErrorHandler
+}
+
+// A TCPConfig is used to configure a tcp socket writer.
+// For details of the fields, see tcp.Config.
+type TCPConfig struct {
+	Addr string
+}
+
+// A UnixConfig is used to configure a unix domain socket writer.
+// For details of the fields, see unix.Config.
+type UnixConfig struct {
+	Pathname    string
+	Perm        FileMode
+	NoOverwrite bool
+}
+
+// UnmarshalJSON implements the interface json.Unmarshaler.
+// Unknown fields of the Type are rejected.
+func (config *WriterConfig) UnmarshalJSON(data []byte) error {
+	typ, err := probeType(data)
+	if err != nil {
+		return err
+	}
+	config.Type = typ
+	var fields interface{}
+	switch typ {
+	case "":
+		config.Type = "stderr"
+		fields = &struct{ Type string }{}
+	case "stderr", "stdout", "null":
+		fields = &struct{ Type string }{}
+	case "file":
+		fields = &struct {
+			Type string
+			*FileConfig
+		}{FileConfig: &config.File}
+	case "syslog":
+		fields = &struct {
+			Type string
+			*SyslogConfig
+		}{SyslogConfig: &config.Syslog}
+	case "tcp":
+		fields = &struct {
+			Type string
+			*TCPConfig
+		}{TCPConfig: &config.TCP}
+	case "unix":
+		fields = &struct {
+			Type string
+			*UnixConfig
+		}{UnixConfig: &config.Unix}
+	default:
+		return fmt.Errorf("unknown writer type: %q", typ)
+	}
+	if err := decodeStrict(data, fields); err != nil {
+		return fmt.Errorf("%s writer: %v", config.Type, err)
+	}
+	return nil
+}
+
+func (config *WriterConfig) check() error {
+	switch config.Type {
+	case "", "stderr", "stdout", "null", "tcp", "unix":
+	case "file":
+		if err := config.File.ErrorHandler.check(); err != nil {
+			return err
+		}
+		if config.File.GzipLevel < flate.HuffmanOnly ||
+			config.File.GzipLevel > flate.BestCompression {
+			return fmt.Errorf("invalid GzipLevel: %d", config.File.GzipLevel)
+		}
+	case "syslog":
+		if err := config.Syslog.ErrorHandler.check(); err != nil {
+			return err
+		}
+		for level := range config.Syslog.SeverityMap {
+			if level < iface.Trace || level > iface.Fatal {
+				return fmt.Errorf("invalid level in SeverityMap: %v", level)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown writer type: %q", config.Type)
+	}
+	return nil
+}
+
+// open opens the writer. The returned closer is nil if the writer need NOT
+// be closed.
+func (config *WriterConfig) open() (iface.Writer, io.Closer, error) {
+	switch config.Type {
+	case "stdout":
+		return writer.Wrap(os.Stdout, nil), nil, nil
+	case "null":
+		return writer.Null(), nil, nil
+	case "file":
+		wt, err := file.Open(config.File.config())
+		if err != nil {
+			return nil, nil, err
+		}
+		return wt, wt, nil
+	case "syslog":
+		wt, err := syslog.Open(config.Syslog.config())
+		if err != nil {
+			return nil, nil, err
+		}
+		return wt, wt, nil
+	case "tcp":
+		wt, err := tcp.Open(tcp.Config{Addr: config.TCP.Addr})
+		if err != nil {
+			return nil, nil, err
+		}
+		return wt, wt, nil
+	case "unix":
+		wt, err := unix.Open(unix.Config{
+			Pathname:    config.Unix.Pathname,
+			Perm:        os.FileMode(config.Unix.Perm),
+			NoOverwrite: config.Unix.NoOverwrite,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return wt, wt, nil
+	}
+	return writer.Wrap(os.Stderr, nil), nil, nil
+}
+
+func (config *FileConfig) config() file.Config {
+	var rotateAt []time.Duration
+	for _, offset := range config.RotateAt {
+		rotateAt = append(rotateAt, time.Duration(offset))
+	}
+	var preamble func(*file.PreambleInfo) []byte
+	if config.Preamble {
+		preamble = file.DefaultPreamble
+	}
+	return file.Config{
+		Path:           config.Path,
+		Filename:       config.Filename,
+		ReopenOnSignal: config.ReopenOnSignal,
+		Base:           config.Base,
+		Ext:            config.Ext,
+		Separator:      config.Separator,
+		DateStyle:      file.DateStyle(config.DateStyle),
+		TimeStyle:      file.TimeStyle(config.TimeStyle),
+		Location:       config.Location.location(),
+		RotateEvery:    time.Duration(config.RotateEvery),
+		RotateAt:       rotateAt,
+		MaxFileSize:    config.MaxFileSize,
+		Append:         config.Append,
+		Shared:         config.Shared,
+		CheckInterval:  time.Duration(config.CheckInterval),
+		BufferSize:     config.BufferSize,
+		FlushInterval:  time.Duration(config.FlushInterval),
+		Sync: file.SyncPolicy{
+			EveryN:   config.Sync.EveryN,
+			Interval: time.Duration(config.Sync.Interval),
+			Level:    config.Sync.Level,
+		},
+		Compression:   file.Compression(config.Compression),
+		CompressLevel: config.CompressLevel,
+		GzipLevel:     config.GzipLevel,
+		AESKey:        config.AESKey,
+		BlockMode:     file.BlockCipherMode(config.BlockMode),
+		PublicKey:     config.PublicKey,
+		KeyID:         config.KeyID,
+		ErrorHandler:  config.ErrorHandler.handler(),
+		Preamble:      preamble,
+		DirPerm:       os.FileMode(config.DirPerm),
+		NoDirForDays:  config.NoDirForDays,
+		Symlink:       config.Symlink,
+		MaxFiles:      config.MaxFiles,
+		MaxAge:        time.Duration(config.MaxAge),
+		MaxTotalSize:  config.MaxTotalSize,
+		MinFreeSpace:  config.MinFreeSpace,
+		MaxDirUsage:   config.MaxDirUsage,
+		SpacePolicy:   file.SpacePolicy(config.SpacePolicy),
+		SpaceLevel:    config.SpaceLevel,
+	}
+}
+
+func (config *SyslogConfig) config() syslog.Config {
+	severityMap := make(map[iface.Level]syslog.Severity, len(config.SeverityMap))
+	for level, severity := range config.SeverityMap {
+		severityMap[level] = syslog.Severity(severity)
+	}
+	return syslog.Config{
+		Tag:          config.Tag,
+		Facility:     syslog.Facility(config.Facility),
+		Network:      config.Network,
+		Addr:         config.Addr,
+		SeverityMap:  severityMap,
+		ErrorHandler: config.ErrorHandler.handler(),
+	}
+}
+
+func (handler ErrorHandler) check() error {
+	switch handler {
+	case "", "Report", "ReportDetails":
+		return nil
+	}
+	return fmt.Errorf("unknown ErrorHandler: %q", string(handler))
+}
+
+func (handler ErrorHandler) handler() writer.ErrorHandler {
+	switch handler {
+	case "Report":
+		return writer.Report
+	case "ReportDetails":
+		return writer.ReportDetails
+	}
+	return nil
+}
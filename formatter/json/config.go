@@ -4,7 +4,7 @@ package json
 type OmitBits int
 
 // All available flags here. If a flag is set, the corresponding field of a
-// Record will be omitted.
+// Record will be omitted. The Name field is always omitted when it is empty.
 const (
 	Time OmitBits = 0x1 << iota
 	Level
@@ -16,6 +16,7 @@ const (
 	Prefix
 	Context
 	Mark
+	Name
 	Aux = Prefix | Context | Mark
 )
 
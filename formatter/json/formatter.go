@@ -65,6 +65,10 @@ func (formatter *Formatter) Format(record *iface.Record) []byte {
 		buf = formatStrField(buf, sep, "Func", fn, false)
 		sep = ","
 	}
+	if formatter.config.Omit&Name == 0 && record.Name != "" {
+		buf = formatStrField(buf, sep, "Name", record.Name, true)
+		sep = ","
+	}
 	if formatter.config.Omit&Msg == 0 {
 		buf = formatStrField(buf, sep, "Msg", record.Msg, true)
 		sep = ","
@@ -143,7 +147,8 @@ func formatContexts(buf []byte, sep string, contexts []iface.Context) []byte {
 		buf = append(buf, sep...)
 		buf = append(buf, "{"...)
 		buf = formatStrField(buf, "", "Key", context.Key, true)
-		buf = formatStrField(buf, ",", "Value", context.Value, true)
+		buf = append(buf, `,"Value":`...)
+		buf = formatValue(buf, context.Value)
 		buf = append(buf, "}"...)
 		sep = ","
 	}
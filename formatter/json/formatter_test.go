@@ -0,0 +1,97 @@
+package json_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gxlog/gxlog/formatter/json"
+	"github.com/gxlog/gxlog/iface"
+)
+
+func TestTypedContexts(t *testing.T) {
+	formatter := json.New(json.Config{
+		Omit: json.Time | json.Level | json.File | json.Line | json.Pkg |
+			json.Func | json.Prefix | json.Mark,
+	})
+	record := &iface.Record{
+		Msg: "testing",
+		Aux: iface.Auxiliary{
+			Contexts: []iface.Context{
+				{Key: "str", Value: "v"},
+				{Key: "int", Value: 42},
+				{Key: "float", Value: 0.5},
+				{Key: "bool", Value: true},
+				{Key: "nil", Value: nil},
+				{Key: "time", Value: time.Date(2018, 8, 1, 7, 12, 7, 0, time.UTC)},
+				{Key: "duration", Value: time.Second},
+				{Key: "error", Value: errors.New("failed")},
+				{Key: "bytes", Value: []byte("gx")},
+				{Key: "object", Value: []iface.Context{{Key: "k", Value: 1}}},
+				{Key: "map", Value: map[string]interface{}{"b": 2, "a": "x"}},
+			},
+		},
+	}
+	expect := `{"Msg":"testing","Aux":{"Contexts":[` +
+		`{"Key":"str","Value":"v"},` +
+		`{"Key":"int","Value":42},` +
+		`{"Key":"float","Value":0.5},` +
+		`{"Key":"bool","Value":true},` +
+		`{"Key":"nil","Value":null},` +
+		`{"Key":"time","Value":"2018-08-01T07:12:07Z"},` +
+		`{"Key":"duration","Value":1000000000},` +
+		`{"Key":"error","Value":"failed"},` +
+		`{"Key":"bytes","Value":"Z3g="},` +
+		`{"Key":"object","Value":{"k":1}},` +
+		`{"Key":"map","Value":{"a":"x","b":2}}` +
+		"]}}\n"
+	output := string(formatter.Format(record))
+	if output != expect {
+		t.Errorf("TestTypedContexts:\noutput: %q\nexpect: %q", output, expect)
+	}
+}
+
+type nilError struct {
+	msg string
+}
+
+func (err *nilError) Error() string {
+	return err.msg
+}
+
+func TestNilError(t *testing.T) {
+	formatter := json.New(json.Config{
+		Omit: json.Time | json.Level | json.File | json.Line | json.Pkg |
+			json.Func | json.Msg | json.Prefix | json.Mark,
+	})
+	var err *nilError
+	record := &iface.Record{
+		Aux: iface.Auxiliary{
+			Contexts: []iface.Context{{Key: "error", Value: err}},
+		},
+	}
+	expect := `{"Aux":{"Contexts":[{"Key":"error","Value":"<nil>"}]}}` + "\n"
+	output := string(formatter.Format(record))
+	if output != expect {
+		t.Errorf("TestNilError:\noutput: %q\nexpect: %q", output, expect)
+	}
+}
+
+func TestName(t *testing.T) {
+	formatter := json.New(json.Config{
+		Omit: json.Time | json.Level | json.File | json.Line | json.Pkg |
+			json.Func | json.Aux,
+	})
+	record := &iface.Record{Msg: "testing"}
+	expect := `{"Msg":"testing"}` + "\n"
+	output := string(formatter.Format(record))
+	if output != expect {
+		t.Errorf("TestName:\noutput: %q\nexpect: %q", output, expect)
+	}
+	record.Name = "db.conn"
+	expect = `{"Name":"db.conn","Msg":"testing"}` + "\n"
+	output = string(formatter.Format(record))
+	if output != expect {
+		t.Errorf("TestName:\noutput: %q\nexpect: %q", output, expect)
+	}
+}
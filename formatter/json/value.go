@@ -0,0 +1,129 @@
+package json
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gxlog/gxlog/iface"
+)
+
+// formatValue appends the value of a context with its real json type.
+// Integers and floats are output as json numbers, bools as json booleans,
+// nil as json null, a []iface.Context or a map[string]interface{} as a json
+// object. A time.Time is output in the format of time.RFC3339Nano, a
+// time.Duration as an integer of nanoseconds and a []byte in base64 the same
+// as the package encoding/json does. Any other value is output as a json
+// string in the manner of fmt.Sprint.
+func formatValue(buf []byte, value interface{}) []byte {
+	switch value := value.(type) {
+	case nil:
+		return append(buf, "null"...)
+	case string:
+		return formatStr(buf, value)
+	case bool:
+		return strconv.AppendBool(buf, value)
+	case int:
+		return strconv.AppendInt(buf, int64(value), 10)
+	case int8:
+		return strconv.AppendInt(buf, int64(value), 10)
+	case int16:
+		return strconv.AppendInt(buf, int64(value), 10)
+	case int32:
+		return strconv.AppendInt(buf, int64(value), 10)
+	case int64:
+		return strconv.AppendInt(buf, value, 10)
+	case uint:
+		return strconv.AppendUint(buf, uint64(value), 10)
+	case uint8:
+		return strconv.AppendUint(buf, uint64(value), 10)
+	case uint16:
+		return strconv.AppendUint(buf, uint64(value), 10)
+	case uint32:
+		return strconv.AppendUint(buf, uint64(value), 10)
+	case uint64:
+		return strconv.AppendUint(buf, value, 10)
+	case uintptr:
+		return strconv.AppendUint(buf, uint64(value), 10)
+	case float32:
+		return formatFloat(buf, float64(value), 32)
+	case float64:
+		return formatFloat(buf, value, 64)
+	case time.Time:
+		buf = append(buf, `"`...)
+		buf = value.AppendFormat(buf, time.RFC3339Nano)
+		return append(buf, `"`...)
+	case time.Duration:
+		return strconv.AppendInt(buf, int64(value), 10)
+	case error:
+		return formatStr(buf, errorString(value))
+	case []byte:
+		buf = append(buf, `"`...)
+		buf = append(buf, base64.StdEncoding.EncodeToString(value)...)
+		return append(buf, `"`...)
+	case []iface.Context:
+		return formatObject(buf, value)
+	case map[string]interface{}:
+		return formatMap(buf, value)
+	}
+	return formatStr(buf, fmt.Sprint(value))
+}
+
+// errorString returns the message of err. A nil pointer that implements the
+// interface error is formatted as "<nil>" in the manner of fmt.Sprint rather
+// than calling its Error method which may panic.
+func errorString(err error) string {
+	if value := reflect.ValueOf(err); value.Kind() == reflect.Ptr && value.IsNil() {
+		return "<nil>"
+	}
+	return err.Error()
+}
+
+func formatStr(buf []byte, str string) []byte {
+	buf = append(buf, `"`...)
+	buf = escape(buf, str)
+	return append(buf, `"`...)
+}
+
+func formatFloat(buf []byte, value float64, bitSize int) []byte {
+	// NaN and infinities are NOT valid json numbers
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return formatStr(buf, strconv.FormatFloat(value, 'g', -1, bitSize))
+	}
+	return strconv.AppendFloat(buf, value, 'g', -1, bitSize)
+}
+
+func formatObject(buf []byte, contexts []iface.Context) []byte {
+	buf = append(buf, "{"...)
+	for i, context := range contexts {
+		if i > 0 {
+			buf = append(buf, ","...)
+		}
+		buf = formatStr(buf, context.Key)
+		buf = append(buf, ":"...)
+		buf = formatValue(buf, context.Value)
+	}
+	return append(buf, "}"...)
+}
+
+func formatMap(buf []byte, kvs map[string]interface{}) []byte {
+	keys := make([]string, 0, len(kvs))
+	for key := range kvs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	buf = append(buf, "{"...)
+	for i, key := range keys {
+		if i > 0 {
+			buf = append(buf, ","...)
+		}
+		buf = formatStr(buf, key)
+		buf = append(buf, ":"...)
+		buf = formatValue(buf, kvs[key])
+	}
+	return append(buf, "}"...)
+}
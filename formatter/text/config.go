@@ -35,6 +35,7 @@ type Config struct {
 	//   line    |                          |           %d |
 	//   pkg     | <lastSegs>               | 0         %s | 0, 1, 2, ...
 	//   func    | <lastSegs>               | 0         %s | 0, 1, 2, ...
+	//   name    | <lastSegs>               | 0         %s | 0, 1, 2, ...
 	//   prefix  |                          |           %s |
 	//   context | <pair|list>              | "pair"    %s | "pair", "list"
 	//   msg     |                          |           %s |
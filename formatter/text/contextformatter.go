@@ -2,6 +2,7 @@ package text
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/gxlog/gxlog/iface"
@@ -45,7 +46,7 @@ func formatPair(buf []byte, contexts []iface.Context) []byte {
 		buf = append(buf, left...)
 		buf = append(buf, ctx.Key...)
 		buf = append(buf, ": "...)
-		buf = append(buf, ctx.Value...)
+		buf = appendValue(buf, ctx.Value)
 		buf = append(buf, ')')
 		left = " ("
 	}
@@ -58,8 +59,32 @@ func formatList(buf []byte, contexts []iface.Context) []byte {
 		buf = append(buf, begin...)
 		buf = append(buf, ctx.Key...)
 		buf = append(buf, ": "...)
-		buf = append(buf, ctx.Value...)
+		buf = appendValue(buf, ctx.Value)
 		begin = ", "
 	}
 	return buf
 }
+
+// appendValue appends the value in the manner of fmt.Sprint. The most common
+// types are handled without fmt for performance.
+func appendValue(buf []byte, value interface{}) []byte {
+	switch value := value.(type) {
+	case string:
+		return append(buf, value...)
+	case int:
+		return strconv.AppendInt(buf, int64(value), 10)
+	case int64:
+		return strconv.AppendInt(buf, value, 10)
+	case int32:
+		return strconv.AppendInt(buf, int64(value), 10)
+	case uint:
+		return strconv.AppendUint(buf, uint64(value), 10)
+	case uint64:
+		return strconv.AppendUint(buf, value, 10)
+	case uint32:
+		return strconv.AppendUint(buf, uint64(value), 10)
+	case bool:
+		return strconv.AppendBool(buf, value)
+	}
+	return fmt.Append(buf, value)
+}
@@ -130,6 +130,30 @@ func TestColor(t *testing.T) {
 	testFormat(t, formatter, record, expect)
 }
 
+func TestTypedContexts(t *testing.T) {
+	formatter := text.New(text.Config{
+		Header: "{{context}}",
+	})
+	record := cloneRecord()
+	record.Aux.Contexts = []iface.Context{
+		{Key: "int", Value: -42},
+		{Key: "float", Value: 0.5},
+		{Key: "bool", Value: true},
+		{Key: "duration", Value: time.Second},
+	}
+	expect := "(int: -42) (float: 0.5) (bool: true) (duration: 1s)"
+	testFormat(t, formatter, record, expect)
+}
+
+func TestNameElement(t *testing.T) {
+	formatter := text.New(text.Config{
+		Header: "{{name}} {{name:1}} {{name%6s}}",
+	})
+	record := cloneRecord()
+	record.Name = "db.pool"
+	testFormat(t, formatter, record, "db.pool pool db.pool")
+}
+
 func testFormat(t *testing.T, formatter iface.Formatter, record *iface.Record,
 	expect string) {
 
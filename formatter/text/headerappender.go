@@ -15,6 +15,7 @@ var newFormatterFuncMap = map[string]func(property, fmtspec string) elementForma
 	"line":    newLineFormatter,
 	"pkg":     newPkgFormatter,
 	"func":    newFuncFormatter,
+	"name":    newNameFormatter,
 	"msg":     newMsgFormatter,
 	"prefix":  newPrefixFormatter,
 	"context": newContextFormatter,
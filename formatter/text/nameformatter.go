@@ -0,0 +1,33 @@
+package text
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gxlog/gxlog/formatter/internal/util"
+	"github.com/gxlog/gxlog/iface"
+)
+
+type nameFormatter struct {
+	segments int
+	fmtspec  string
+}
+
+func newNameFormatter(property, fmtspec string) elementFormatter {
+	if fmtspec == "" {
+		fmtspec = "%s"
+	}
+	segments, _ := strconv.Atoi(property)
+	return &nameFormatter{
+		segments: segments,
+		fmtspec:  fmtspec,
+	}
+}
+
+func (formatter *nameFormatter) FormatElement(buf []byte, record *iface.Record) []byte {
+	name := util.LastSegments(record.Name, formatter.segments, '.')
+	if formatter.fmtspec == "%s" {
+		return append(buf, name...)
+	}
+	return append(buf, fmt.Sprintf(formatter.fmtspec, name)...)
+}
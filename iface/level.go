@@ -0,0 +1,58 @@
+package iface
+
+import (
+	"fmt"
+	"strings"
+)
+
+var levelNames = []string{
+	Trace: "Trace",
+	Debug: "Debug",
+	Info:  "Info",
+	Warn:  "Warn",
+	Error: "Error",
+	Fatal: "Fatal",
+	Off:   "Off",
+}
+
+// String returns the name of the level, e.g. "Info".
+func (level Level) String() string {
+	if level >= Trace && level <= Off {
+		return levelNames[level]
+	}
+	return fmt.Sprintf("Level(%d)", int(level))
+}
+
+// ParseLevel returns the level with the name. The name is case-insensitive.
+// "warning" is accepted as an alias of Warn.
+func ParseLevel(name string) (Level, error) {
+	name = strings.TrimSpace(name)
+	for level := Trace; level <= Off; level++ {
+		if strings.EqualFold(name, levelNames[level]) {
+			return level, nil
+		}
+	}
+	if strings.EqualFold(name, "warning") {
+		return Warn, nil
+	}
+	return 0, fmt.Errorf("unknown level: %q", name)
+}
+
+// MarshalText implements the interface encoding.TextMarshaler.
+func (level Level) MarshalText() ([]byte, error) {
+	if level < Trace || level > Off {
+		return nil, fmt.Errorf("invalid level: %d", int(level))
+	}
+	return []byte(levelNames[level]), nil
+}
+
+// UnmarshalText implements the interface encoding.TextUnmarshaler.
+// It accepts the same names as ParseLevel.
+func (level *Level) UnmarshalText(text []byte) error {
+	parsed, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*level = parsed
+	return nil
+}
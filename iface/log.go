@@ -22,9 +22,14 @@ const (
 const LevelCount = 6
 
 // A Context is a pair of key-value that is associated with a log.
+//
+// The Value keeps the type it is passed with, e.g. int64, float64, bool,
+// time.Time, time.Duration, error or []byte. A nested object is represented
+// by a []Context or a map[string]interface{}. It is up to a Formatter how to
+// render the Value. A Formatter falls back to fmt.Sprint for unknown types.
 type Context struct {
 	Key   string
-	Value string
+	Value interface{}
 }
 
 // An Auxiliary is a set of extra attributes that are associated with a log.
@@ -44,6 +49,7 @@ type Record struct {
 	Func  string
 	Msg   string
 	Aux   Auxiliary
+	Name  string
 }
 
 // Formatter is the interface that a formatter of a Logger needs to implement.
@@ -57,6 +63,9 @@ type Formatter interface {
 
 // Writer is the interface that a writer of a Logger needs to implement.
 // A Writer must NOT modify the bs and record.
+// The record is reused by the Logger after Write returns. A Writer needs to
+// make a copy of the record if it is used after Write returns, e.g. in case
+// of asynchrony.
 //
 // Do NOT call any method of the Logger within Write, or it may deadlock.
 type Writer interface {
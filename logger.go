@@ -2,6 +2,7 @@
 package gxlog
 
 import (
+	"context"
 	"os"
 
 	"github.com/gxlog/gxlog/formatter/text"
@@ -31,3 +32,13 @@ func Logger() *logger.Logger {
 func Formatter() *text.Formatter {
 	return defaultFormatter
 }
+
+// FromContext returns the Logger carried by the ctx with the ctx bound to it.
+// If the ctx carries no Logger, it returns the default Logger with the ctx
+// bound to it.
+func FromContext(ctx context.Context) *logger.Logger {
+	if log := logger.FromContext(ctx); log != nil {
+		return log
+	}
+	return defaultLogger.WithCtx(ctx)
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -27,10 +28,12 @@ type locator struct {
 }
 
 type copyOnWrite struct {
+	Name            string
 	Prefix          string
 	Contexts        []iface.Context
 	DynamicContexts []dynamicContext
 	Marked          bool
+	Ctx             context.Context
 	CountLimiter    Filter
 	TimeLimiter     Filter
 }
@@ -51,6 +54,8 @@ func (log *Logger) WithPrefix(prefix string) *Logger {
 // The kvs is regarded as an interleaved key-value sequence,
 // e.g. key1, value1, key2, value2 ...
 // If the count of the arguments is odd, the last argument will be ignored.
+// A key is converted to a string in the manner of fmt.Sprint while a value
+// keeps its type, such that a Formatter can render it with its real type.
 //
 // WithContext also supports dynamic contexts. If a value of type Dynamic is as
 // the value of a key-value pair passed to WithContext, it will be regarded as
@@ -154,7 +159,7 @@ func appendContexts(contexts []iface.Context, dynamicContexts []dynamicContext,
 		} else {
 			contexts = append(contexts, iface.Context{
 				Key:   fmt.Sprint(kvs[0]),
-				Value: fmt.Sprint(kvs[1]),
+				Value: kvs[1],
 			})
 		}
 		kvs = kvs[2:]
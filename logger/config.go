@@ -1,6 +1,9 @@
 package logger
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/gxlog/gxlog/iface"
 )
 
@@ -18,6 +21,77 @@ const (
 	Runtime
 )
 
+var flagNames = []struct {
+	Flag Flag
+	Name string
+}{
+	{Prefix, "Prefix"},
+	{StaticContext, "StaticContext"},
+	{DynamicContext, "DynamicContext"},
+	{Mark, "Mark"},
+	{LimitByCount, "LimitByCount"},
+	{LimitByTime, "LimitByTime"},
+	{Runtime, "Runtime"},
+}
+
+// String returns the names of the flags joined by '|', e.g. "Prefix|Mark".
+func (flags Flag) String() string {
+	var names []string
+	for _, flag := range flagNames {
+		if flags&flag.Flag != 0 {
+			names = append(names, flag.Name)
+			flags &^= flag.Flag
+		}
+	}
+	if flags != 0 {
+		names = append(names, fmt.Sprintf("%#x", int(flags)))
+	}
+	return strings.Join(names, "|")
+}
+
+// ParseFlags returns the flags with the names joined by '|'. The names are
+// case-insensitive. An empty text means no flag.
+func ParseFlags(text string) (Flag, error) {
+	var flags Flag
+	for _, name := range strings.Split(text, "|") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		flag, ok := lookupFlag(name)
+		if !ok {
+			return 0, fmt.Errorf("unknown flag: %q", name)
+		}
+		flags |= flag
+	}
+	return flags, nil
+}
+
+// MarshalText implements the interface encoding.TextMarshaler.
+func (flags Flag) MarshalText() ([]byte, error) {
+	return []byte(flags.String()), nil
+}
+
+// UnmarshalText implements the interface encoding.TextUnmarshaler.
+// It accepts the same text as ParseFlags.
+func (flags *Flag) UnmarshalText(text []byte) error {
+	parsed, err := ParseFlags(string(text))
+	if err != nil {
+		return err
+	}
+	*flags = parsed
+	return nil
+}
+
+func lookupFlag(name string) (Flag, bool) {
+	for _, flag := range flagNames {
+		if strings.EqualFold(name, flag.Name) {
+			return flag.Flag, true
+		}
+	}
+	return 0, false
+}
+
 // The Filter type defines a function type which is used to filter logs.
 //
 // Do NOT call any method of the Logger within a filter, or it may deadlock.
@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gxlog/gxlog/iface"
+)
+
+type ctxKey struct{}
+
+// The Extractor type defines a function type which is used to extract the value
+// of a context key-value pair from a context.Context when a log is emitted.
+// If it returns false, the key-value pair will NOT be attached to the log.
+//
+// Do NOT call any method of the Logger within the function, or it may deadlock.
+type Extractor func(ctx context.Context, key interface{}) (interface{}, bool)
+
+type ctxExtractor struct {
+	Key     interface{}
+	Extract Extractor
+}
+
+// NewContext returns a copy of the ctx which carries the log.
+func NewContext(ctx context.Context, log *Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, log)
+}
+
+// FromContext returns the Logger carried by the ctx with the ctx bound to it
+// by WithCtx. If the ctx carries no Logger, it returns nil.
+func FromContext(ctx context.Context) *Logger {
+	log, ok := ctx.Value(ctxKey{}).(*Logger)
+	if !ok {
+		return nil
+	}
+	return log.WithCtx(ctx)
+}
+
+// WithCtx returns a new Logger that is a shallow copy of the Logger.
+// With the new Logger, all the logs it outputs will have the key-value pairs
+// extracted from the ctx by the registered extractors attached as long as the
+// DynamicContext flag is NOT disabled. The extracted key-value pairs will be
+// concatenated to the end of dynamic contexts.
+func (log *Logger) WithCtx(ctx context.Context) *Logger {
+	clone := *log
+	clone.attr.Ctx = ctx
+	return &clone
+}
+
+// RegisterExtractor registers the extractor with the key. If the key has been
+// registered, the extractor will replace the previous one. The key MUST be
+// comparable and the extractor must NOT be nil.
+//
+// The extractors are shared by the Logger and all the Loggers derived from it,
+// and they are called in the order of registration with the context.Context
+// bound by WithCtx whenever a log is emitted.
+func (log *Logger) RegisterExtractor(key interface{}, extractor Extractor) {
+	log.lock.Lock()
+	defer log.lock.Unlock()
+
+	for i := range *log.extractors {
+		if (*log.extractors)[i].Key == key {
+			(*log.extractors)[i].Extract = extractor
+			return
+		}
+	}
+	*log.extractors = append(*log.extractors, ctxExtractor{
+		Key:     key,
+		Extract: extractor,
+	})
+}
+
+// UnregisterExtractor unregisters the extractor with the key.
+func (log *Logger) UnregisterExtractor(key interface{}) {
+	log.lock.Lock()
+	defer log.lock.Unlock()
+
+	extractors := *log.extractors
+	for i := range extractors {
+		if extractors[i].Key == key {
+			*log.extractors = append(extractors[:i:i], extractors[i+1:]...)
+			return
+		}
+	}
+}
+
+func (log *Logger) appendExtracted(contexts []iface.Context) []iface.Context {
+	if log.attr.Ctx == nil {
+		return contexts
+	}
+	for _, extractor := range *log.extractors {
+		value, ok := extractor.Extract(log.attr.Ctx, extractor.Key)
+		if ok {
+			contexts = append(contexts, iface.Context{
+				Key:   fmt.Sprint(extractor.Key),
+				Value: value,
+			})
+		}
+	}
+	return contexts
+}
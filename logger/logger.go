@@ -12,6 +12,7 @@ import (
 	"runtime/debug"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gxlog/gxlog/iface"
@@ -19,35 +20,54 @@ import (
 
 const callDepthOffset = 3
 
-// A Logger is a logging framework that contains EIGHT slots. Each Slot contains
+var recordPool = sync.Pool{
+	New: func() interface{} {
+		return new(iface.Record)
+	},
+}
+
+// A Logger is a logging framework that contains slots. Each Slot contains
 // a Formatter and a Writer. A Logger has its own level and filter while each
 // Slot has its independent level and filter. Logger calls the Formatter and
-// Writer of each Slot in the order from Slot0 to Slot7 when a log is emitted.
+// Writer of each Slot in the order from Slot0 to the last Slot when a log is
+// emitted. A Logger has the EIGHT predefined slots from Slot0 to Slot7, and
+// more slots can be linked by value or by name with LinkNamed.
 //
 // All methods of A Logger are concurrency safe.
 // A Logger MUST be created with New.
 type Logger struct {
-	config *Config
-	slots  []slotLink
-	// store indexes of equivalent formatters, used to avoid redundant formatting
-	equivalents [][]int
-	countMap    map[locator]int64
-	timeMap     map[locator]*timeQueue
-	attr        copyOnWrite
-	lock        *sync.Mutex
+	config   *Config
+	slots    *slotTable
+	countMap map[locator]int64
+	timeMap  map[locator]*timeQueue
+	// levels set to hierarchical names
+	nameLevels map[string]iface.Level
+	modules    *moduleTable
+	// the levels shared by clones to reject logs without locking
+	gate *levelGate
+	// the *configLevels of the config, replaced along with the config
+	configLevels atomic.Value
+	extractors   *[]ctxExtractor
+	attr         copyOnWrite
+	lock         *sync.Mutex
 }
 
 // New creates a new Logger with the config.
 func New(config Config) *Logger {
 	config.setDefaults()
 	logger := &Logger{
-		config:      &config,
-		equivalents: make([][]int, MaxSlot),
-		countMap:    make(map[locator]int64, mapInitCap),
-		timeMap:     make(map[locator]*timeQueue, mapInitCap),
-		lock:        new(sync.Mutex),
+		config:     &config,
+		countMap:   make(map[locator]int64, mapInitCap),
+		timeMap:    make(map[locator]*timeQueue, mapInitCap),
+		nameLevels: make(map[string]iface.Level),
+		modules:    new(moduleTable),
+		gate:       new(levelGate),
+		extractors: new([]ctxExtractor),
+		lock:       new(sync.Mutex),
 	}
+	logger.configLevels.Store(new(configLevels))
 	logger.initSlots()
+	logger.updateMinLevel()
 	return logger
 }
 
@@ -111,6 +131,36 @@ func (log *Logger) Fatalf(fmtstr string, args ...interface{}) {
 	log.Logf(1, iface.Fatal, fmtstr, args...)
 }
 
+// Tracew calls Logw with level Trace to emit a log.
+func (log *Logger) Tracew(msg string, kvs ...interface{}) {
+	log.Logw(1, iface.Trace, msg, kvs...)
+}
+
+// Debugw calls Logw with level Debug to emit a log.
+func (log *Logger) Debugw(msg string, kvs ...interface{}) {
+	log.Logw(1, iface.Debug, msg, kvs...)
+}
+
+// Infow calls Logw with level Info to emit a log.
+func (log *Logger) Infow(msg string, kvs ...interface{}) {
+	log.Logw(1, iface.Info, msg, kvs...)
+}
+
+// Warnw calls Logw with level Warn to emit a log.
+func (log *Logger) Warnw(msg string, kvs ...interface{}) {
+	log.Logw(1, iface.Warn, msg, kvs...)
+}
+
+// Errorw calls Logw with level Error to emit a log.
+func (log *Logger) Errorw(msg string, kvs ...interface{}) {
+	log.Logw(1, iface.Error, msg, kvs...)
+}
+
+// Fatalw calls Logw with level Fatal to emit a log.
+func (log *Logger) Fatalw(msg string, kvs ...interface{}) {
+	log.Logw(1, iface.Fatal, msg, kvs...)
+}
+
 // LogError calls Log to emit a log and calls errors.New to return an error.
 // The level MUST be between Trace and Fatal inclusive.
 func (log *Logger) LogError(level iface.Level, text string) error {
@@ -138,18 +188,22 @@ func (log *Logger) LogErrorf(level iface.Level, fmtstr string, args ...interface
 // The callDepth is used to set the offset of stack. It makes sense when you are
 // customizing your own log wrapper function. Otherwise, 0 is just ok.
 //
-// The args are handled in the manner of fmt.Sprint.
+// The args are handled in the manner of fmt.Sprint. If the log will NOT be
+// output by any Slot, neither the args are formatted nor a Record is created.
 //
 // ATTENTION: the log may NOT be output when a Writer is in asynchronous mode and
 // os.Exit has been called.
 func (log *Logger) Log(callDepth int, level iface.Level, args ...interface{}) {
-	logLevel, trackLevel, exitLevel := log.levels()
+	if !log.accepts(level) {
+		return
+	}
+	logLevel, trackLevel, exitLevel := log.levels(callDepth)
 	if logLevel <= level {
 		if trackLevel <= level {
 			stack := debug.Stack()
 			args = append(args, "\n", string(stack[:len(stack)-1]))
 		}
-		log.write(callDepth, level, fmt.Sprint(args...))
+		log.write(callDepth, level, fmt.Sprint(args...), nil)
 		if exitLevel <= level {
 			os.Exit(1)
 		}
@@ -161,14 +215,45 @@ func (log *Logger) Log(callDepth int, level iface.Level, args ...interface{}) {
 // ATTENTION: the log may NOT be output when a Writer is in asynchronous mode and
 // os.Exit has been called.
 func (log *Logger) Logf(callDepth int, level iface.Level, fmtstr string, args ...interface{}) {
-	logLevel, trackLevel, exitLevel := log.levels()
+	if !log.accepts(level) {
+		return
+	}
+	logLevel, trackLevel, exitLevel := log.levels(callDepth)
 	if logLevel <= level {
 		if trackLevel <= level {
 			fmtstr += "\n%s"
 			stack := debug.Stack()
 			args = append(args, stack[:len(stack)-1])
 		}
-		log.write(callDepth, level, fmt.Sprintf(fmtstr, args...))
+		log.write(callDepth, level, fmt.Sprintf(fmtstr, args...), nil)
+		if exitLevel <= level {
+			os.Exit(1)
+		}
+	}
+}
+
+// Logw does the same with Log except that it takes the msg as is and attaches
+// the kvs as contexts to the log without cloning the Logger.
+//
+// The kvs is regarded as an interleaved key-value sequence in the same manner
+// as WithContext, including the support for values of type Dynamic. The
+// key-value pairs are appended to the end of the contexts of the Logger.
+// A static pair is attached as long as the StaticContext flag is NOT disabled,
+// and a dynamic pair as long as the DynamicContext flag is NOT disabled.
+//
+// ATTENTION: the log may NOT be output when a Writer is in asynchronous mode and
+// os.Exit has been called.
+func (log *Logger) Logw(callDepth int, level iface.Level, msg string, kvs ...interface{}) {
+	if !log.accepts(level) {
+		return
+	}
+	logLevel, trackLevel, exitLevel := log.levels(callDepth)
+	if logLevel <= level {
+		if trackLevel <= level {
+			stack := debug.Stack()
+			msg += "\n" + string(stack[:len(stack)-1])
+		}
+		log.write(callDepth, level, msg, kvs)
 		if exitLevel <= level {
 			os.Exit(1)
 		}
@@ -192,7 +277,7 @@ func (log *Logger) Panic(args ...interface{}) {
 	msg := fmt.Sprint(args...)
 	logLevel, panicLevel := log.panicLevel()
 	if logLevel <= panicLevel {
-		log.write(0, panicLevel, msg)
+		log.write(0, panicLevel, msg, nil)
 	}
 	panic(msg)
 }
@@ -206,7 +291,7 @@ func (log *Logger) Panicf(fmtstr string, args ...interface{}) {
 	msg := fmt.Sprintf(fmtstr, args...)
 	logLevel, panicLevel := log.panicLevel()
 	if logLevel <= panicLevel {
-		log.write(0, panicLevel, msg)
+		log.write(0, panicLevel, msg, nil)
 	}
 	panic(msg)
 }
@@ -241,28 +326,50 @@ func (log *Logger) Timingf(fmtstr string, args ...interface{}) func() {
 	return func() {}
 }
 
-func (log *Logger) levels() (iface.Level, iface.Level, iface.Level) {
+// accepts reports whether the log of the level may pass the level of the
+// Logger, of its names or modules, and then be output by any slot or cause
+// exiting.
+func (log *Logger) accepts(level iface.Level) bool {
+	levels := log.configLevels.Load().(*configLevels)
+	filterLevel := atomic.LoadInt32(&log.gate.filter)
+	if configLevel := atomic.LoadInt32(&levels.level); configLevel < filterLevel {
+		filterLevel = configLevel
+	}
+	slotLevel := atomic.LoadInt32(&log.gate.slot)
+	if exitLevel := atomic.LoadInt32(&levels.exit); exitLevel < slotLevel {
+		slotLevel = exitLevel
+	}
+	return iface.Level(filterLevel) <= level && iface.Level(slotLevel) <= level
+}
+
+func (log *Logger) levels(callDepth int) (iface.Level, iface.Level, iface.Level) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	return log.config.Level, log.config.TrackLevel, log.config.ExitLevel
+	level, ok := log.moduleLevel(callDepth)
+	if !ok {
+		level = log.level()
+	}
+	return level, log.config.TrackLevel, log.config.ExitLevel
 }
 
 func (log *Logger) timingLevel() (iface.Level, iface.Level) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	return log.config.Level, log.config.TimingLevel
+	return log.level(), log.config.TimingLevel
 }
 
 func (log *Logger) panicLevel() (iface.Level, iface.Level) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	return log.config.Level, log.config.PanicLevel
+	return log.level(), log.config.PanicLevel
 }
 
-func (log *Logger) write(callDepth int, level iface.Level, msg string) {
+func (log *Logger) write(callDepth int, level iface.Level, msg string,
+	kvs []interface{}) {
+
 	if level < iface.Trace || level > iface.Fatal {
 		panic("logger: invalid level")
 	}
@@ -272,29 +379,42 @@ func (log *Logger) write(callDepth int, level iface.Level, msg string) {
 		file, line, pkg, fn = getPosInfo(callDepth + callDepthOffset)
 	}
 
-	log.lock.Lock()
-	defer log.lock.Unlock()
-
-	record := &iface.Record{
+	record := recordPool.Get().(*iface.Record)
+	*record = iface.Record{
 		Time:  time.Now(),
 		Level: level,
 		File:  file,
 		Line:  line,
 		Pkg:   pkg,
 		Func:  fn,
+		Name:  log.attr.Name,
 		Msg:   msg,
 	}
 
+	log.lock.Lock()
+	log.emit(record, kvs)
+	log.lock.Unlock()
+
+	*record = iface.Record{}
+	recordPool.Put(record)
+}
+
+func (log *Logger) emit(record *iface.Record, kvs []interface{}) {
 	if !log.filter(record) {
 		return
 	}
 
-	log.attachAux(record)
+	log.attachAux(record, kvs)
 
-	var formats [MaxSlot][]byte
-	for slot := 0; slot < MaxSlot; slot++ {
-		link := &log.slots[slot]
-		if link.Level > level {
+	// formats of the predefined slots are on the stack to avoid allocation
+	var predefined [MaxSlot][]byte
+	formats := predefined[:]
+	if len(log.slots.links) > len(predefined) {
+		formats = make([][]byte, len(log.slots.links))
+	}
+	for slot := range log.slots.links {
+		link := &log.slots.links[slot]
+		if link.Level > record.Level {
 			continue
 		}
 		if link.Filter != nil && !link.Filter(record) {
@@ -303,7 +423,7 @@ func (log *Logger) write(callDepth int, level iface.Level, msg string) {
 		format := formats[slot]
 		if format == nil {
 			format = link.Formatter.Format(record)
-			for _, id := range log.equivalents[slot] {
+			for _, id := range log.slots.equivalents[slot] {
 				formats[id] = format
 			}
 		}
@@ -328,7 +448,7 @@ func (log *Logger) filter(record *iface.Record) bool {
 	return true
 }
 
-func (log *Logger) attachAux(record *iface.Record) {
+func (log *Logger) attachAux(record *iface.Record, kvs []interface{}) {
 	if log.config.Disabled&Prefix == 0 {
 		record.Aux.Prefix = log.attr.Prefix
 	}
@@ -339,22 +459,47 @@ func (log *Logger) attachAux(record *iface.Record) {
 		for _, context := range log.attr.DynamicContexts {
 			record.Aux.Contexts = append(record.Aux.Contexts, iface.Context{
 				Key:   fmt.Sprint(context.Key),
-				Value: fmt.Sprint(context.Value(context.Key)),
+				Value: context.Value(context.Key),
 			})
 		}
+		record.Aux.Contexts = log.appendExtracted(record.Aux.Contexts)
 	}
+	record.Aux.Contexts = log.appendPairs(record.Aux.Contexts, kvs)
 	if log.config.Disabled&Mark == 0 {
 		record.Aux.Marked = log.attr.Marked
 	}
 }
 
+func (log *Logger) appendPairs(contexts []iface.Context,
+	kvs []interface{}) []iface.Context {
+
+	for len(kvs) >= 2 {
+		dynamic, ok := kvs[1].(Dynamic)
+		if ok {
+			if log.config.Disabled&DynamicContext == 0 {
+				contexts = append(contexts, iface.Context{
+					Key:   fmt.Sprint(kvs[0]),
+					Value: dynamic(kvs[0]),
+				})
+			}
+		} else if log.config.Disabled&StaticContext == 0 {
+			contexts = append(contexts, iface.Context{
+				Key:   fmt.Sprint(kvs[0]),
+				Value: kvs[1],
+			})
+		}
+		kvs = kvs[2:]
+	}
+	return contexts
+}
+
 func (log *Logger) genDone(msg string) func() {
 	now := time.Now()
 	return func() {
 		cost := time.Since(now)
 		logLevel, timingLevel := log.timingLevel()
 		if logLevel <= timingLevel {
-			log.write(0, timingLevel, fmt.Sprintf("%s (cost: %v)", msg, cost))
+			log.write(0, timingLevel, fmt.Sprintf("%s (cost: %v)", msg, cost), nil)
 		}
 	}
 }
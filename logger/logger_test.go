@@ -0,0 +1,282 @@
+package logger_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gxlog/gxlog/formatter"
+	"github.com/gxlog/gxlog/iface"
+	"github.com/gxlog/gxlog/logger"
+	"github.com/gxlog/gxlog/writer"
+)
+
+type countingFormatter struct {
+	count int
+}
+
+func (formatter *countingFormatter) Format(*iface.Record) []byte {
+	formatter.count++
+	return []byte("formatted")
+}
+
+func newRejectingLogger() *logger.Logger {
+	log := logger.New(logger.Config{})
+	log.Link(logger.Slot0, formatter.Null(), writer.Null(), iface.Warn)
+	log.Link(logger.Slot1, formatter.Null(), writer.Null(), iface.Error)
+	return log
+}
+
+func TestRejectedLogAllocs(t *testing.T) {
+	log := newRejectingLogger()
+	allocs := testing.AllocsPerRun(100, func() {
+		log.Info("rejected by all slots")
+		log.Infof("rejected by %s", "all slots")
+	})
+	if allocs != 0 {
+		t.Errorf("TestRejectedLogAllocs: %v allocs per run, expect 0", allocs)
+	}
+
+	log.SetLevel(iface.Fatal)
+	allocs = testing.AllocsPerRun(100, func() {
+		log.Warn("rejected by the logger")
+	})
+	if allocs != 0 {
+		t.Errorf("TestRejectedLogAllocs: %v allocs per run, expect 0", allocs)
+	}
+}
+
+func TestAcceptedLog(t *testing.T) {
+	log := logger.New(logger.Config{})
+	var msgs []string
+	hook := writer.Func(func(_ []byte, record *iface.Record) {
+		msgs = append(msgs, record.Msg)
+	})
+	log.Link(logger.Slot0, formatter.Null(), hook, iface.Warn)
+	log.Info("rejected")
+	log.Warn("accepted")
+	log.SetSlotLevel(logger.Slot0, iface.Trace)
+	log.Info("accepted")
+	if len(msgs) != 2 {
+		t.Errorf("TestAcceptedLog: %d logs output, expect 2", len(msgs))
+	}
+}
+
+func TestCloneSetConfig(t *testing.T) {
+	log := logger.New(logger.Config{Level: iface.Warn})
+	var msgs []string
+	hook := writer.Func(func(_ []byte, record *iface.Record) {
+		msgs = append(msgs, record.Msg)
+	})
+	log.Link(logger.Slot0, formatter.Null(), hook, iface.Trace)
+	clone := log.WithPrefix("clone")
+
+	// the config of the clone is NOT replaced
+	log.SetConfig(logger.Config{Level: iface.Error})
+	log.Warn("rejected")
+	clone.Warn("accepted")
+
+	clone.SetConfig(logger.Config{Level: iface.Debug})
+	log.Debug("rejected")
+	clone.Debug("accepted")
+	if len(msgs) != 2 {
+		t.Errorf("TestCloneSetConfig: logs: %v", msgs)
+	}
+}
+
+func TestKeyValues(t *testing.T) {
+	log := logger.New(logger.Config{})
+	var contexts []iface.Context
+	hook := writer.Func(func(_ []byte, record *iface.Record) {
+		contexts = record.Aux.Contexts
+	})
+	log.Link(logger.Slot0, formatter.Null(), hook)
+	dynamic := logger.Dynamic(func(interface{}) interface{} { return "dv" })
+	clog := log.WithContext("static", 1, "dynamic", dynamic)
+	clog.Infow("key-values", "k1", 2, "k2", dynamic, "odd")
+
+	expect := []iface.Context{
+		{Key: "static", Value: 1},
+		{Key: "dynamic", Value: "dv"},
+		{Key: "k1", Value: 2},
+		{Key: "k2", Value: "dv"},
+	}
+	if len(contexts) != len(expect) {
+		t.Fatalf("TestKeyValues: contexts: %v, expect: %v", contexts, expect)
+	}
+	for i := range expect {
+		if contexts[i] != expect[i] {
+			t.Errorf("TestKeyValues: contexts: %v, expect: %v", contexts, expect)
+		}
+	}
+
+	clog.Info("no key-values")
+	if len(contexts) != 2 {
+		t.Errorf("TestKeyValues: contexts: %v, expect 2 contexts", contexts)
+	}
+}
+
+func TestContextExtractors(t *testing.T) {
+	type reqIDKey struct{}
+	log := logger.New(logger.Config{})
+	var contexts []iface.Context
+	hook := writer.Func(func(_ []byte, record *iface.Record) {
+		contexts = record.Aux.Contexts
+	})
+	log.Link(logger.Slot0, formatter.Null(), hook)
+	log.RegisterExtractor("reqID",
+		func(ctx context.Context, _ interface{}) (interface{}, bool) {
+			value := ctx.Value(reqIDKey{})
+			return value, value != nil
+		})
+
+	ctx := logger.NewContext(context.Background(), log.WithContext("k", "v"))
+	logger.FromContext(ctx).Info("no request id")
+	if len(contexts) != 1 {
+		t.Errorf("TestContextExtractors: contexts: %v, expect 1 context", contexts)
+	}
+
+	ctx = context.WithValue(ctx, reqIDKey{}, 42)
+	logger.FromContext(ctx).Info("request id")
+	expect := []iface.Context{{Key: "k", Value: "v"}, {Key: "reqID", Value: 42}}
+	if len(contexts) != 2 || contexts[0] != expect[0] || contexts[1] != expect[1] {
+		t.Errorf("TestContextExtractors: contexts: %v, expect: %v", contexts, expect)
+	}
+
+	if logger.FromContext(context.Background()) != nil {
+		t.Errorf("TestContextExtractors: expect no Logger in the context")
+	}
+}
+
+func TestNamedSlots(t *testing.T) {
+	log := logger.New(logger.Config{})
+	var order []string
+	shared := new(countingFormatter)
+	newHook := func(name string) iface.Writer {
+		return writer.Func(func([]byte, *iface.Record) {
+			order = append(order, name)
+		})
+	}
+	log.Link(logger.Slot0, shared, newHook("slot0"))
+	names := []string{"console", "file", "errors", "audit", "syslog",
+		"socket", "metrics", "capture", "extra"}
+	for i, name := range names {
+		slot := log.LinkNamed(name, shared, newHook(name))
+		if slot != logger.Slot(logger.MaxSlot+i) {
+			t.Errorf("TestNamedSlots: slot of %q: %d", name, slot)
+		}
+	}
+	if slot, ok := log.NamedSlot("errors"); !ok || log.SlotName(slot) != "errors" {
+		t.Errorf("TestNamedSlots: slot of %q not found", "errors")
+	}
+	log.Info("named slots")
+
+	if len(order) != len(names)+1 || order[0] != "slot0" ||
+		order[len(order)-1] != "extra" {
+		t.Errorf("TestNamedSlots: order: %v", order)
+	}
+	if shared.count != 1 {
+		t.Errorf("TestNamedSlots: formatted %d times, expect 1", shared.count)
+	}
+}
+
+func TestNameLevels(t *testing.T) {
+	log := logger.New(logger.Config{Level: iface.Info})
+	var names []string
+	hook := writer.Func(func(_ []byte, record *iface.Record) {
+		names = append(names, record.Name)
+	})
+	log.Link(logger.Slot0, formatter.Null(), hook)
+	levels, err := logger.ParseNameLevels("db=Debug, db.pool=trace,http=Warn")
+	if err != nil {
+		t.Fatalf("TestNameLevels: %v", err)
+	}
+	log.SetNameLevels(levels)
+
+	db := log.Named("db")
+	db.Trace("rejected")
+	db.Debug("accepted")
+	db.Named("pool").Trace("accepted")
+	db.Named("conn").Trace("rejected")
+	log.Named("http").Info("rejected")
+	log.Debug("rejected")
+
+	expect := []string{"db", "db.pool"}
+	if len(names) != len(expect) || names[0] != expect[0] || names[1] != expect[1] {
+		t.Errorf("TestNameLevels: names: %v, expect: %v", names, expect)
+	}
+
+	if _, err := logger.ParseNameLevels("db=verbose"); err == nil {
+		t.Errorf("TestNameLevels: expect an error of unknown level")
+	}
+}
+
+func TestModuleLevels(t *testing.T) {
+	log := logger.New(logger.Config{Level: iface.Info})
+	count := 0
+	hook := writer.Func(func([]byte, *iface.Record) {
+		count++
+	})
+	log.Link(logger.Slot0, formatter.Null(), hook)
+
+	specs := []struct {
+		spec  string
+		count int
+	}{
+		{"", 0},
+		{"github.com/gxlog/gxlog/logger_test=Trace", 3},
+		{"github.com/gxlog/gxlog/logger=Trace", 0},
+		{"github.com/gxlog/gxlog/*=Debug", 2},
+		{"logger/logger_test.go=Trace,*=Off", 3},
+		{"logger.go=Trace", 0},
+	}
+	for _, spec := range specs {
+		if err := log.SetModuleLevels(spec.spec); err != nil {
+			t.Fatalf("TestModuleLevels: %v", err)
+		}
+		count = 0
+		for i := 0; i < 2; i++ {
+			log.Trace("trace")
+			log.Debugf("%s", "debug")
+			log.Logw(0, iface.Debug, "debug")
+		}
+		if count != spec.count*2 {
+			t.Errorf("TestModuleLevels: spec: %q, count: %d, expect: %d",
+				spec.spec, count, spec.count*2)
+		}
+	}
+
+	if err := log.SetModuleLevels("handler.go=Loud"); err == nil {
+		t.Errorf("TestModuleLevels: expect an error of unknown level")
+	}
+	if log.ModuleLevels() != "logger.go=Trace" {
+		t.Errorf("TestModuleLevels: module levels changed on error")
+	}
+}
+
+func BenchmarkRejectedLog(b *testing.B) {
+	log := newRejectingLogger()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		log.Info("rejected by all slots")
+	}
+}
+
+func BenchmarkRejectedLogf(b *testing.B) {
+	log := newRejectingLogger()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		log.Infof("rejected by %s", "all slots")
+	}
+}
+
+func BenchmarkAcceptedLog(b *testing.B) {
+	log := logger.New(logger.Config{})
+	log.Link(logger.Slot0, formatter.Null(), writer.Null())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		log.Info("accepted by a slot")
+	}
+}
@@ -0,0 +1,157 @@
+package logger
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+	"strings"
+
+	"github.com/gxlog/gxlog/iface"
+)
+
+// the offset of stack from moduleLevel to the caller of Log, Logf or Logw
+const moduleCallDepthOffset = 4
+
+type moduleLevel struct {
+	Pattern string
+	Level   iface.Level
+	// whether the Pattern matches files rather than packages
+	File bool
+}
+
+type moduleTable struct {
+	spec   string
+	levels []moduleLevel
+	// resolved level of each call site, 0 means no module level matches
+	cache map[uintptr]iface.Level
+}
+
+// ModuleLevels returns the spec of module levels of the Logger.
+func (log *Logger) ModuleLevels() string {
+	log.lock.Lock()
+	defer log.lock.Unlock()
+
+	return log.modules.spec
+}
+
+// SetModuleLevels sets the module levels of the Logger according to the spec.
+// If the spec is invalid, it returns an error and the module levels of the
+// Logger are left to be unchanged.
+//
+// The spec is a comma separated list of <pattern>=<level>, e.g.
+// "github.com/acme/db/*=Trace,handler.go=Debug". A pattern ending with ".go"
+// matches the trailing segments of the File of a log, otherwise it matches the
+// Pkg of a log. A pattern is in the syntax of path.Match. The first pattern
+// that matches wins. The level names are case-insensitive. An empty spec
+// removes all the module levels.
+//
+// The level of a matched call site takes the place of the level of the Logger
+// when a log is emitted with Log, Logf, Logw or any of their helper methods.
+// The matching is done only once for each call site, and the result is cached.
+func (log *Logger) SetModuleLevels(spec string) error {
+	levels, err := parseModuleLevels(spec)
+	if err != nil {
+		return fmt.Errorf("logger.SetModuleLevels: %v", err)
+	}
+
+	log.lock.Lock()
+	defer log.lock.Unlock()
+
+	log.modules.spec = spec
+	log.modules.levels = levels
+	log.modules.cache = make(map[uintptr]iface.Level)
+	log.updateMinLevel()
+	return nil
+}
+
+// CheckModuleLevels checks the spec of module levels. For details of the spec,
+// see SetModuleLevels.
+func CheckModuleLevels(spec string) error {
+	if _, err := parseModuleLevels(spec); err != nil {
+		return fmt.Errorf("logger.CheckModuleLevels: %v", err)
+	}
+	return nil
+}
+
+func parseModuleLevels(spec string) ([]moduleLevel, error) {
+	var levels []moduleLevel
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		pos := strings.LastIndexByte(item, '=')
+		if pos < 0 {
+			return nil, fmt.Errorf("missing '=' in %q", item)
+		}
+		pattern := strings.TrimSpace(item[:pos])
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+		level, err := iface.ParseLevel(item[pos+1:])
+		if err != nil {
+			return nil, err
+		}
+		levels = append(levels, moduleLevel{
+			Pattern: pattern,
+			Level:   level,
+			File:    strings.HasSuffix(pattern, ".go"),
+		})
+	}
+	return levels, nil
+}
+
+// moduleLevel returns the module level of the call site at callDepth.
+// If no module level matches, it returns false.
+func (log *Logger) moduleLevel(callDepth int) (iface.Level, bool) {
+	if len(log.modules.levels) == 0 {
+		return 0, false
+	}
+	var pcs [1]uintptr
+	if runtime.Callers(callDepth+moduleCallDepthOffset, pcs[:]) == 0 {
+		return 0, false
+	}
+	level, ok := log.modules.cache[pcs[0]]
+	if !ok {
+		level = log.matchModule(pcs[0])
+		log.modules.cache[pcs[0]] = level
+	}
+	return level, level != 0
+}
+
+func (log *Logger) matchModule(pc uintptr) iface.Level {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	pkg, _ := splitPkgAndFunc(frame.Function)
+	for _, module := range log.modules.levels {
+		name := pkg
+		if module.File {
+			name = lastSegments(frame.File, strings.Count(module.Pattern, "/")+1)
+		}
+		if ok, _ := path.Match(module.Pattern, name); ok {
+			return module.Level
+		}
+	}
+	return 0
+}
+
+func (log *Logger) minModuleLevel() iface.Level {
+	level := iface.Off
+	for _, module := range log.modules.levels {
+		if module.Level < level {
+			level = module.Level
+		}
+	}
+	return level
+}
+
+func lastSegments(pathname string, n int) string {
+	for i := len(pathname) - 1; i >= 0; i-- {
+		if pathname[i] == '/' {
+			n--
+			if n == 0 {
+				return pathname[i+1:]
+			}
+		}
+	}
+	return pathname
+}
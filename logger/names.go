@@ -0,0 +1,141 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gxlog/gxlog/iface"
+)
+
+// NameSeparator is the separator of segments of a hierarchical name.
+const NameSeparator = "."
+
+// Named returns a new Logger that is a shallow copy of the Logger.
+// The name of the new Logger is the name of the Logger joined with the name
+// by NameSeparator, e.g. log.Named("db").Named("pool") is named "db.pool".
+// The name is attached to all the logs the new Logger outputs.
+//
+// The level of the new Logger is the level set to the longest name of the
+// hierarchy with SetNameLevel. If no name of the hierarchy has a level set,
+// the level of the Logger is used.
+func (log *Logger) Named(name string) *Logger {
+	clone := *log
+	if clone.attr.Name == "" {
+		clone.attr.Name = name
+	} else {
+		clone.attr.Name += NameSeparator + name
+	}
+	return &clone
+}
+
+// Name returns the hierarchical name of the Logger.
+func (log *Logger) Name() string {
+	return log.attr.Name
+}
+
+// NameLevel returns the level set to the name. If no level is set to the name,
+// it returns false.
+func (log *Logger) NameLevel(name string) (iface.Level, bool) {
+	log.lock.Lock()
+	defer log.lock.Unlock()
+
+	level, ok := log.nameLevels[name]
+	return level, ok
+}
+
+// SetNameLevel sets the level to the name. The level applies to all the
+// Loggers with the name and their descendants unless a descendant name has
+// a level set.
+func (log *Logger) SetNameLevel(name string, level iface.Level) {
+	log.lock.Lock()
+	defer log.lock.Unlock()
+
+	log.nameLevels[name] = level
+	log.updateMinLevel()
+}
+
+// UnsetNameLevel removes the level set to the name.
+func (log *Logger) UnsetNameLevel(name string) {
+	log.lock.Lock()
+	defer log.lock.Unlock()
+
+	delete(log.nameLevels, name)
+	log.updateMinLevel()
+}
+
+// NameLevels returns a copy of the levels set to names.
+func (log *Logger) NameLevels() map[string]iface.Level {
+	log.lock.Lock()
+	defer log.lock.Unlock()
+
+	levels := make(map[string]iface.Level, len(log.nameLevels))
+	for name, level := range log.nameLevels {
+		levels[name] = level
+	}
+	return levels
+}
+
+// SetNameLevels replaces all the levels set to names with the levels.
+func (log *Logger) SetNameLevels(levels map[string]iface.Level) {
+	log.lock.Lock()
+	defer log.lock.Unlock()
+
+	for name := range log.nameLevels {
+		delete(log.nameLevels, name)
+	}
+	for name, level := range levels {
+		log.nameLevels[name] = level
+	}
+	log.updateMinLevel()
+}
+
+// ParseNameLevels parses the spec of levels of names, which is a comma
+// separated list of <name>=<level>, e.g. "db=Debug,db.pool=Trace,http=Warn".
+// The level names are case-insensitive.
+func ParseNameLevels(spec string) (map[string]iface.Level, error) {
+	levels := make(map[string]iface.Level)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		pos := strings.LastIndexByte(item, '=')
+		if pos < 0 {
+			return nil, fmt.Errorf("logger.ParseNameLevels: missing '=' in %q", item)
+		}
+		level, err := iface.ParseLevel(item[pos+1:])
+		if err != nil {
+			return nil, fmt.Errorf("logger.ParseNameLevels: %v", err)
+		}
+		levels[strings.TrimSpace(item[:pos])] = level
+	}
+	return levels, nil
+}
+
+// level returns the level of the Logger with its name resolved.
+func (log *Logger) level() iface.Level {
+	name := log.attr.Name
+	if name == "" || len(log.nameLevels) == 0 {
+		return log.config.Level
+	}
+	for {
+		if level, ok := log.nameLevels[name]; ok {
+			return level
+		}
+		pos := strings.LastIndex(name, NameSeparator)
+		if pos < 0 {
+			return log.config.Level
+		}
+		name = name[:pos]
+	}
+}
+
+func (log *Logger) minNameLevel() iface.Level {
+	level := iface.Off
+	for _, nameLevel := range log.nameLevels {
+		if nameLevel < level {
+			level = nameLevel
+		}
+	}
+	return level
+}
@@ -19,6 +19,9 @@ func (log *Logger) SetConfig(config Config) {
 
 	config.setDefaults()
 	log.config = &config
+	// the clones with the previous config are NOT affected
+	log.configLevels.Store(new(configLevels))
+	log.updateMinLevel()
 }
 
 // UpdateConfig calls the fn with the Config of the Logger, and then sets the
@@ -31,6 +34,8 @@ func (log *Logger) UpdateConfig(fn func(Config) Config) {
 
 	config := fn(*log.config)
 	log.config = &config
+	log.configLevels.Store(new(configLevels))
+	log.updateMinLevel()
 }
 
 // Level returns the level of the Logger.
@@ -47,6 +52,7 @@ func (log *Logger) SetLevel(level iface.Level) {
 	defer log.lock.Unlock()
 
 	log.config.Level = level
+	log.updateMinLevel()
 }
 
 // TrackLevel returns the track level of the Logger.
@@ -79,6 +85,7 @@ func (log *Logger) SetExitLevel(level iface.Level) {
 	defer log.lock.Unlock()
 
 	log.config.ExitLevel = level
+	log.updateMinLevel()
 }
 
 // TimingLevel returns the timing level of the Logger.
@@ -3,16 +3,18 @@ package logger
 import (
 	"fmt"
 	"reflect"
+	"sync/atomic"
 
 	"github.com/gxlog/gxlog/formatter"
 	"github.com/gxlog/gxlog/iface"
 	"github.com/gxlog/gxlog/writer"
 )
 
-// The Slot defines the slot type of Logger.
+// The Slot defines the slot type of Logger. Any non-negative value is a valid
+// Slot. A Slot that has NOT been linked is a null slot.
 type Slot int
 
-// All available slots here.
+// All predefined slots here.
 const (
 	Slot0 Slot = iota
 	Slot1
@@ -24,7 +26,9 @@ const (
 	Slot7
 )
 
-// MaxSlot is the total count of slots.
+// MaxSlot is the count of the predefined slots. A Logger is NOT limited to
+// MaxSlot slots, slots with a greater value or created by LinkNamed are also
+// available.
 const MaxSlot = 8
 
 type slotLink struct {
@@ -40,6 +44,13 @@ var nullSlotLink = slotLink{
 	Level:     iface.Off,
 }
 
+type slotTable struct {
+	links []slotLink
+	names []string
+	// store indexes of equivalent formatters, used to avoid redundant formatting
+	equivalents [][]int
+}
+
 // Link sets the formatter and writer to the slot. The opts is used to specify
 // the slot Level and/or the slot Filter. An opt MUST be a value of type Level,
 // Filter or func(*Record)bool (the underlying type of Filter).
@@ -48,32 +59,67 @@ var nullSlotLink = slotLink{
 func (log *Logger) Link(slot Slot, formatter iface.Formatter,
 	writer iface.Writer, opts ...interface{}) {
 
-	link := slotLink{
-		Formatter: formatter,
-		Writer:    writer,
-		Level:     iface.Trace,
+	link := newSlotLink(formatter, writer, opts)
+
+	log.lock.Lock()
+	defer log.lock.Unlock()
+
+	*log.slot(slot) = link
+	log.updateSlots()
+}
+
+// LinkNamed does the same with Link except that the slot is specified by the
+// name. If there is no slot with the name, a new slot is created after all
+// the existing slots. It returns the Slot, which can be used as a handle with
+// all the other slot methods.
+func (log *Logger) LinkNamed(name string, formatter iface.Formatter,
+	writer iface.Writer, opts ...interface{}) Slot {
+
+	link := newSlotLink(formatter, writer, opts)
+
+	log.lock.Lock()
+	defer log.lock.Unlock()
+
+	slot, ok := log.namedSlot(name)
+	if !ok {
+		slot = Slot(len(log.slots.links))
+		log.slot(slot)
+		log.slots.names[slot] = name
 	}
+	log.slots.links[slot] = link
+	log.updateSlots()
+	return slot
+}
 
-	for _, opt := range opts {
-		switch opt := opt.(type) {
-		case iface.Level:
-			link.Level = opt
-		case Filter:
-			link.Filter = opt
-		case func(*iface.Record) bool:
-			link.Filter = opt
-		case nil:
-			// noop
-		default:
-			panic(fmt.Sprintf("logger.Link: unknown link option type: %T", opt))
-		}
+// NamedSlot returns the Slot with the name. If there is no slot with the name,
+// it returns false.
+func (log *Logger) NamedSlot(name string) (Slot, bool) {
+	log.lock.Lock()
+	defer log.lock.Unlock()
+
+	return log.namedSlot(name)
+}
+
+// SlotName returns the name of the slot. The name of a slot NOT created by
+// LinkNamed is empty.
+func (log *Logger) SlotName(slot Slot) string {
+	log.lock.Lock()
+	defer log.lock.Unlock()
+
+	if int(slot) < len(log.slots.names) {
+		return log.slots.names[slot]
 	}
+	return ""
+}
 
+// SlotCount returns the count of slots of the Logger. It is NOT less than
+// MaxSlot and all the slots in [0, SlotCount()) are called in order when a log
+// is emitted.
+func (log *Logger) SlotCount() int {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.slots[slot] = link
-	log.updateEquivalents()
+	return len(log.slots.links)
 }
 
 // Unlink sets the Formatter, Writer and Filter of the slot to nil and
@@ -82,8 +128,8 @@ func (log *Logger) Unlink(slot Slot) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.slots[slot] = nullSlotLink
-	log.updateEquivalents()
+	*log.slot(slot) = nullSlotLink
+	log.updateSlots()
 }
 
 // UnlinkAll sets the Formatter, Writer and Filter of all slots to nil and
@@ -92,10 +138,10 @@ func (log *Logger) UnlinkAll() {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	for i := range log.slots {
-		log.slots[i] = nullSlotLink
+	for i := range log.slots.links {
+		log.slots.links[i] = nullSlotLink
 	}
-	log.updateEquivalents()
+	log.updateSlots()
 }
 
 // CopySlot copies the Formatter, Writer, Level and Filter of Slot src
@@ -104,8 +150,9 @@ func (log *Logger) CopySlot(dst, src Slot) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.slots[dst] = log.slots[src]
-	log.updateEquivalents()
+	log.reserve(dst, src)
+	log.slots.links[dst] = log.slots.links[src]
+	log.updateSlots()
 }
 
 // MoveSlot copies the Formatter, Writer, Level and Filter of Slot from
@@ -114,9 +161,10 @@ func (log *Logger) MoveSlot(to, from Slot) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.slots[to] = log.slots[from]
-	log.slots[from] = nullSlotLink
-	log.updateEquivalents()
+	log.reserve(to, from)
+	log.slots.links[to] = log.slots.links[from]
+	log.slots.links[from] = nullSlotLink
+	log.updateSlots()
 }
 
 // SwapSlot swaps the Formatter, Writer, Level and Filter of the slots.
@@ -124,8 +172,10 @@ func (log *Logger) SwapSlot(left, right Slot) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.slots[left], log.slots[right] = log.slots[right], log.slots[left]
-	log.updateEquivalents()
+	log.reserve(left, right)
+	links := log.slots.links
+	links[left], links[right] = links[right], links[left]
+	log.updateSlots()
 }
 
 // SlotFormatter returns the Formatter of the slot.
@@ -133,7 +183,7 @@ func (log *Logger) SlotFormatter(slot Slot) iface.Formatter {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	return log.slots[slot].Formatter
+	return log.slot(slot).Formatter
 }
 
 // SetSlotFormatter sets the Formatter of the slot. The formatter must NOT be nil.
@@ -141,7 +191,7 @@ func (log *Logger) SetSlotFormatter(slot Slot, formatter iface.Formatter) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.slots[slot].Formatter = formatter
+	log.slot(slot).Formatter = formatter
 	log.updateEquivalents()
 }
 
@@ -150,7 +200,7 @@ func (log *Logger) SlotWriter(slot Slot) iface.Writer {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	return log.slots[slot].Writer
+	return log.slot(slot).Writer
 }
 
 // SetSlotWriter sets the Writer of the slot. The writer must NOT be nil.
@@ -158,7 +208,7 @@ func (log *Logger) SetSlotWriter(slot Slot, writer iface.Writer) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.slots[slot].Writer = writer
+	log.slot(slot).Writer = writer
 }
 
 // SlotLevel returns the Level of the slot.
@@ -166,7 +216,7 @@ func (log *Logger) SlotLevel(slot Slot) iface.Level {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	return log.slots[slot].Level
+	return log.slot(slot).Level
 }
 
 // SetSlotLevel sets the Level of the slot.
@@ -174,7 +224,8 @@ func (log *Logger) SetSlotLevel(slot Slot, level iface.Level) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.slots[slot].Level = level
+	log.slot(slot).Level = level
+	log.updateMinLevel()
 }
 
 // SlotFilter returns the Filter of the slot.
@@ -182,7 +233,7 @@ func (log *Logger) SlotFilter(slot Slot) Filter {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	return log.slots[slot].Filter
+	return log.slot(slot).Filter
 }
 
 // SetSlotFilter sets the Filter of the slot.
@@ -190,27 +241,122 @@ func (log *Logger) SetSlotFilter(slot Slot, filter Filter) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.slots[slot].Filter = filter
+	log.slot(slot).Filter = filter
+}
+
+func newSlotLink(formatter iface.Formatter, writer iface.Writer,
+	opts []interface{}) slotLink {
+
+	link := slotLink{
+		Formatter: formatter,
+		Writer:    writer,
+		Level:     iface.Trace,
+	}
+
+	for _, opt := range opts {
+		switch opt := opt.(type) {
+		case iface.Level:
+			link.Level = opt
+		case Filter:
+			link.Filter = opt
+		case func(*iface.Record) bool:
+			link.Filter = opt
+		case nil:
+			// noop
+		default:
+			panic(fmt.Sprintf("logger.Link: unknown link option type: %T", opt))
+		}
+	}
+	return link
 }
 
 func (log *Logger) initSlots() {
-	for slot := 0; slot < MaxSlot; slot++ {
-		log.slots = append(log.slots, nullSlotLink)
+	log.slots = new(slotTable)
+	log.slot(MaxSlot - 1)
+}
+
+// slot returns the link of the slot. The table grows with null slots if
+// the slot does not exist yet.
+func (log *Logger) slot(slot Slot) *slotLink {
+	if slot < 0 {
+		panic("logger: negative slot")
+	}
+	for int(slot) >= len(log.slots.links) {
+		log.slots.links = append(log.slots.links, nullSlotLink)
+		log.slots.names = append(log.slots.names, "")
+		log.slots.equivalents = append(log.slots.equivalents, nil)
 	}
+	return &log.slots.links[slot]
+}
+
+// reserve grows the table to make sure that both slots exist.
+func (log *Logger) reserve(slot, another Slot) {
+	log.slot(slot)
+	log.slot(another)
+}
+
+func (log *Logger) namedSlot(name string) (Slot, bool) {
+	for i, slotName := range log.slots.names {
+		if slotName != "" && slotName == name {
+			return Slot(i), true
+		}
+	}
+	return 0, false
+}
+
+func (log *Logger) updateSlots() {
+	log.updateEquivalents()
+	log.updateMinLevel()
 }
 
 func (log *Logger) updateEquivalents() {
-	for i := 0; i < MaxSlot; i++ {
-		log.equivalents[i] = log.equivalents[i][:0]
-		if !reflect.TypeOf(log.slots[i].Formatter).Comparable() {
+	links := log.slots.links
+	equivalents := log.slots.equivalents
+	for i := range links {
+		equivalents[i] = equivalents[i][:0]
+		if !reflect.TypeOf(links[i].Formatter).Comparable() {
 			continue
 		}
-		for j := i + 1; j < MaxSlot; j++ {
-			if !reflect.TypeOf(log.slots[j].Formatter).Comparable() ||
-				log.slots[i].Formatter != log.slots[j].Formatter {
+		for j := i + 1; j < len(links); j++ {
+			if !reflect.TypeOf(links[j].Formatter).Comparable() ||
+				links[i].Formatter != links[j].Formatter {
 				continue
 			}
-			log.equivalents[i] = append(log.equivalents[i], j)
+			equivalents[i] = append(equivalents[i], j)
 		}
 	}
 }
+
+// A levelGate holds the min levels of the slots, and of the names and the
+// modules, which are shared by clones of a Logger.
+type levelGate struct {
+	slot   int32
+	filter int32
+}
+
+// A configLevels holds the Level and the ExitLevel of a Config, which are
+// shared by the clones with the same Config.
+type configLevels struct {
+	level int32
+	exit  int32
+}
+
+// updateMinLevel updates the levels used to reject logs without locking.
+func (log *Logger) updateMinLevel() {
+	slotLevel := iface.Off
+	for i := range log.slots.links {
+		if log.slots.links[i].Level < slotLevel {
+			slotLevel = log.slots.links[i].Level
+		}
+	}
+	filterLevel := log.minNameLevel()
+	if moduleLevel := log.minModuleLevel(); moduleLevel < filterLevel {
+		filterLevel = moduleLevel
+	}
+	atomic.StoreInt32(&log.gate.slot, int32(slotLevel))
+	atomic.StoreInt32(&log.gate.filter, int32(filterLevel))
+
+	levels := log.configLevels.Load().(*configLevels)
+	atomic.StoreInt32(&levels.level, int32(log.config.Level))
+	atomic.StoreInt32(&levels.exit, int32(log.config.ExitLevel))
+}
@@ -1,33 +1,168 @@
 package writer
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/gxlog/gxlog/iface"
 )
 
+// ErrClosed is reported by an Async when a log is written after it is closed,
+// and returned by Flush after it is closed.
+var ErrClosed = errors.New("writer: the Async is closed")
+
+// The OverflowPolicy defines what an Async does when its channel is full.
+type OverflowPolicy int
+
+// All available overflow policies here.
+const (
+	// blocks until there is room in the channel
+	OverflowBlock OverflowPolicy = iota
+	// blocks at most Timeout and then drops the log
+	OverflowTimeout
+	// drops the log being written
+	OverflowDropNewest
+	// drops the oldest log in the channel to make room
+	OverflowDropOldest
+	// drops the log if its level is lower than Level, otherwise blocks
+	OverflowDropBelow
+)
+
+// An AsyncConfig is used to configure an Async.
+type AsyncConfig struct {
+	// Cap is the capacity of the internal channel of the Async.
+	// It must NOT be negative, and it must NOT be 0 with OverflowDropNewest,
+	// OverflowDropOldest or OverflowDropBelow.
+	Cap int
+	// Overflow is the policy when the channel is full.
+	// If Overflow is not specified, OverflowBlock is used.
+	Overflow OverflowPolicy
+	// Timeout is the max time to block with OverflowTimeout.
+	// If Timeout is not specified, time.Second is used.
+	// It must NOT be negative.
+	Timeout time.Duration
+	// Level is the level of logs NOT to drop with OverflowDropBelow.
+	// If Level is not specified, iface.Warn is used.
+	Level iface.Level
+	// NoticeInterval is the time interval to write a notice of the count of
+	// logs dropped since the last notice, if any, to the underlying writer,
+	// so that the loss is visible in the output.
+	// If NoticeInterval is not specified, (time.Second * 10) is used.
+	// It must NOT be negative.
+	NoticeInterval time.Duration
+	// NoticeFormatter formats the notice, of which the record has the Level
+	// iface.Warn and the Msg "N logs dropped". It is usually the Formatter of
+	// the slot. If NoticeFormatter is nil, the notice is the Msg with the
+	// prefix "gxlog: " and a newline.
+	NoticeFormatter iface.Formatter
+	// NoNotice specifies NOT to write any notice of logs dropped.
+	NoNotice bool
+	// ErrorHandler will be called with ErrClosed when a log is written after
+	// the Async is closed if it is not nil.
+	ErrorHandler ErrorHandler
+}
+
+func (config *AsyncConfig) setDefaults() {
+	if config.Timeout == 0 {
+		config.Timeout = time.Second
+	}
+	if config.Level == 0 {
+		config.Level = iface.Warn
+	}
+	if config.NoticeInterval == 0 {
+		config.NoticeInterval = time.Second * 10
+	}
+}
+
+func (config *AsyncConfig) check() error {
+	if config.Cap < 0 {
+		return errors.New("AsyncConfig.Cap must NOT be negative")
+	}
+	if config.Overflow < OverflowBlock || config.Overflow > OverflowDropBelow {
+		return errors.New("AsyncConfig.Overflow is invalid")
+	}
+	if config.Cap == 0 && config.Overflow >= OverflowDropNewest {
+		return errors.New("AsyncConfig.Cap must NOT be 0 with a drop policy")
+	}
+	if config.Timeout < 0 {
+		return errors.New("AsyncConfig.Timeout must NOT be negative")
+	}
+	if config.NoticeInterval < 0 {
+		return errors.New("AsyncConfig.NoticeInterval must NOT be negative")
+	}
+	return nil
+}
+
 type logData struct {
 	Bytes  []byte
-	Record *iface.Record
+	Record iface.Record
+	// not nil for the marker of Flush, which is closed when it is received
+	Flushed chan struct{}
 }
 
 // An Async is a Writer wrapper.
 // All Writers an Async wraps switch into asynchronous mode.
+// Logs are output by the underlying Writer strictly in the order in which
+// they are written to the Async, except those dropped by the Overflow policy.
 //
 // All methods of an Async are concurrency safe.
-// An Async MUST be created with NewAsync.
+// An Async MUST be created with NewAsync or NewAsyncWithConfig.
 type Async struct {
-	writer    iface.Writer
-	chanData  chan logData
-	chanClose chan struct{}
+	config   AsyncConfig
+	writer   iface.Writer
+	chanData chan logData
+	// the markers of Flush taken out of chanData by OverflowDropOldest, it
+	// has the same capacity as chanData so that a Write rarely waits on it
+	chanMarker chan chan struct{}
+	// closed when the Async starts to close, no more log is accepted
+	chanClosing chan struct{}
+	// closed after all the logs accepted are in chanData
+	chanQuit chan struct{}
+	// closed when the goroutine of the Async exits
+	chanDone  chan struct{}
+	closeOnce sync.Once
+	// Write holds the read lock while sending logs, and the write lock is
+	// used as a barrier to wait for them when closing
+	lock    sync.RWMutex
+	aborted int32
+	// the count of logs dropped in total
+	dropped int64
+	// the count of logs dropped NOT noticed yet
+	unnoticed int64
 }
 
 // NewAsync creates a new Async that wraps the writer. The writer must NOT be nil.
 // The cap is the capacity of the internal channel of the Async and it must NOT
-// be negative.
+// be negative. If the channel is full, Write blocks.
 func NewAsync(writer iface.Writer, cap int) *Async {
+	config := AsyncConfig{Cap: cap}
+	config.setDefaults()
+	return newAsync(writer, config)
+}
+
+// NewAsyncWithConfig creates a new Async with the config that wraps the
+// writer. The writer must NOT be nil.
+func NewAsyncWithConfig(writer iface.Writer, config AsyncConfig) (*Async, error) {
+	config.setDefaults()
+	if err := config.check(); err != nil {
+		return nil, fmt.Errorf("writer.NewAsyncWithConfig: %v", err)
+	}
+	return newAsync(writer, config), nil
+}
+
+func newAsync(writer iface.Writer, config AsyncConfig) *Async {
 	async := &Async{
-		writer:    writer,
-		chanData:  make(chan logData, cap),
-		chanClose: make(chan struct{}),
+		config:      config,
+		writer:      writer,
+		chanData:    make(chan logData, config.Cap),
+		chanMarker:  make(chan chan struct{}, config.Cap),
+		chanClosing: make(chan struct{}),
+		chanQuit:    make(chan struct{}),
+		chanDone:    make(chan struct{}),
 	}
 	go async.serve()
 	return async
@@ -35,27 +170,118 @@ func NewAsync(writer iface.Writer, cap int) *Async {
 
 // Write implements the interface Writer. It sends the bs and record to the
 // internal channel. Another goroutine will receive them from the channel and
-// then calls the underlying Writer with them.
-// If the channel is full, it blocks.
+// then calls the underlying Writer with them. The record is copied because it
+// is reused by the Logger after Write returns.
+// If the channel is full, it acts on the Overflow policy. If the Async is
+// closed, the log is reported to the ErrorHandler with ErrClosed.
 func (async *Async) Write(bs []byte, record *iface.Record) {
-	async.chanData <- logData{Bytes: bs, Record: record}
+	async.lock.RLock()
+	defer async.lock.RUnlock()
+
+	if async.closing() {
+		async.reportClosed(bs, record)
+		return
+	}
+	data := logData{Bytes: bs, Record: *record}
+	select {
+	case async.chanData <- data:
+		return
+	default:
+	}
+
+	switch async.config.Overflow {
+	case OverflowTimeout:
+		timer := time.NewTimer(async.config.Timeout)
+		defer timer.Stop()
+		select {
+		case async.chanData <- data:
+		case <-timer.C:
+			async.drop()
+		case <-async.chanClosing:
+			async.reportClosed(bs, record)
+		}
+		return
+	case OverflowDropNewest:
+		async.drop()
+		return
+	case OverflowDropOldest:
+		for {
+			select {
+			case async.chanData <- data:
+				return
+			default:
+			}
+			select {
+			case oldest := <-async.chanData:
+				if oldest.Flushed != nil {
+					async.chanMarker <- oldest.Flushed
+				} else {
+					async.drop()
+				}
+			default:
+			}
+		}
+	case OverflowDropBelow:
+		if record.Level < async.config.Level {
+			async.drop()
+			return
+		}
+	}
+	select {
+	case async.chanData <- data:
+	case <-async.chanClosing:
+		async.reportClosed(bs, record)
+	}
+}
+
+// Flush waits until all the logs written to the Async before it are output
+// by the underlying Writer. It returns ctx.Err() if the ctx is done before,
+// and ErrClosed if the Async is closed before or aborted.
+func (async *Async) Flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	if err := async.sendMarker(ctx, flushed); err != nil {
+		return err
+	}
+	select {
+	case <-flushed:
+		if atomic.LoadInt32(&async.aborted) != 0 {
+			return ErrClosed
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-// Close closes the internal channel and waits until all logs in the channel
-// have been output. It does NOT close the underlying writer.
+// Close stops accepting logs and waits until all the logs accepted have been
+// output. It does NOT close the underlying writer.
 func (async *Async) Close() {
-	close(async.chanClose)
-	close(async.chanData)
-	for data := range async.chanData {
-		async.writer.Write(data.Bytes, data.Record)
+	async.CloseContext(context.Background())
+}
+
+// CloseContext does the same with Close except that if the ctx is done before
+// all the logs accepted have been output, the rest of them are abandoned and
+// it returns ctx.Err() after the log being output, if any, is done as Abort.
+// So the underlying writer is NOT in use any more when it returns.
+func (async *Async) CloseContext(ctx context.Context) error {
+	async.shutdown()
+	select {
+	case <-async.chanDone:
+		return nil
+	case <-ctx.Done():
+		atomic.StoreInt32(&async.aborted, 1)
+		<-async.chanDone
+		return ctx.Err()
 	}
 }
 
-// Abort closes the internal channel and ignores all logs in the channel.
+// Abort stops accepting logs and abandons all the logs NOT output yet. It
+// waits until the log being output, if any, is done.
 // It does NOT close the underlying writer.
 func (async *Async) Abort() {
-	close(async.chanClose)
-	close(async.chanData)
+	atomic.StoreInt32(&async.aborted, 1)
+	async.shutdown()
+	<-async.chanDone
 }
 
 // Len returns the length of the internal channel.
@@ -63,13 +289,128 @@ func (async *Async) Len() int {
 	return len(async.chanData)
 }
 
+// Dropped returns the count of logs dropped by the Overflow policy in total.
+func (async *Async) Dropped() int64 {
+	return atomic.LoadInt64(&async.dropped)
+}
+
+// serve is the only goroutine that receives logs from chanData, which keeps
+// the order of logs.
 func (async *Async) serve() {
+	defer close(async.chanDone)
+
+	var chanNotice <-chan time.Time
+	if !async.config.NoNotice {
+		ticker := time.NewTicker(async.config.NoticeInterval)
+		defer ticker.Stop()
+		chanNotice = ticker.C
+	}
+	for {
+		select {
+		case data := <-async.chanData:
+			async.output(data)
+		case flushed := <-async.chanMarker:
+			close(flushed)
+		case <-chanNotice:
+			async.notice()
+		case <-async.chanQuit:
+			async.drain()
+			return
+		}
+	}
+}
+
+// drain outputs the logs left in chanData unless aborted.
+func (async *Async) drain() {
 	for {
 		select {
 		case data := <-async.chanData:
-			async.writer.Write(data.Bytes, data.Record)
-		case <-async.chanClose:
+			async.output(data)
+		case flushed := <-async.chanMarker:
+			close(flushed)
+		default:
+			async.notice()
 			return
 		}
 	}
 }
+
+func (async *Async) output(data logData) {
+	if data.Flushed != nil {
+		close(data.Flushed)
+	} else if atomic.LoadInt32(&async.aborted) == 0 {
+		async.writer.Write(data.Bytes, &data.Record)
+	}
+}
+
+func (async *Async) sendMarker(ctx context.Context, flushed chan struct{}) error {
+	async.lock.RLock()
+	defer async.lock.RUnlock()
+
+	if async.closing() {
+		return ErrClosed
+	}
+	select {
+	case async.chanData <- logData{Flushed: flushed}:
+		return nil
+	case <-async.chanClosing:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// shutdown stops accepting logs, waits until the logs being sent are in
+// chanData, and then tells the goroutine to quit after draining chanData.
+func (async *Async) shutdown() {
+	async.closeOnce.Do(func() {
+		close(async.chanClosing)
+		async.lock.Lock()
+		async.lock.Unlock()
+		close(async.chanQuit)
+	})
+}
+
+func (async *Async) closing() bool {
+	select {
+	case <-async.chanClosing:
+		return true
+	default:
+		return false
+	}
+}
+
+func (async *Async) reportClosed(bs []byte, record *iface.Record) {
+	if async.config.ErrorHandler != nil {
+		async.config.ErrorHandler(bs, record, ErrClosed)
+	}
+}
+
+func (async *Async) drop() {
+	atomic.AddInt64(&async.dropped, 1)
+	atomic.AddInt64(&async.unnoticed, 1)
+}
+
+// notice writes a notice of the logs dropped since the last notice, if any,
+// to the underlying writer.
+func (async *Async) notice() {
+	if async.config.NoNotice || atomic.LoadInt32(&async.aborted) != 0 {
+		return
+	}
+	count := atomic.SwapInt64(&async.unnoticed, 0)
+	if count == 0 {
+		return
+	}
+	record := &iface.Record{
+		Time:  time.Now(),
+		Level: iface.Warn,
+		Msg:   fmt.Sprintf("%d logs dropped", count),
+	}
+	var bs []byte
+	if async.config.NoticeFormatter != nil {
+		bs = async.config.NoticeFormatter.Format(record)
+	} else {
+		bs = []byte("gxlog: " + record.Msg + "\n")
+	}
+	async.writer.Write(bs, record)
+}
@@ -0,0 +1,282 @@
+package writer_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gxlog/gxlog/iface"
+	"github.com/gxlog/gxlog/writer"
+)
+
+// blockedWriter blocks at the first log until released and records all logs.
+type blockedWriter struct {
+	blocked chan struct{}
+	release chan struct{}
+	logs    []string
+	lock    sync.Mutex
+}
+
+func newBlockedWriter() *blockedWriter {
+	return &blockedWriter{
+		blocked: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+}
+
+func (wt *blockedWriter) Write(bs []byte, record *iface.Record) {
+	select {
+	case <-wt.blocked:
+	default:
+		close(wt.blocked)
+		<-wt.release
+	}
+	wt.lock.Lock()
+	wt.logs = append(wt.logs, string(bs))
+	wt.lock.Unlock()
+}
+
+// wait waits until the count of logs written reaches the count.
+func (wt *blockedWriter) wait(count int) {
+	for i := 0; i < 1000; i++ {
+		wt.lock.Lock()
+		n := len(wt.logs)
+		wt.lock.Unlock()
+		if n >= count {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	tests := []struct {
+		Config  writer.AsyncConfig
+		Levels  []iface.Level
+		Dropped int64
+		Logs    string
+	}{
+		{
+			Config:  writer.AsyncConfig{Cap: 2, Overflow: writer.OverflowDropNewest},
+			Dropped: 3,
+			Logs:    "0 1 2 gxlog: 3 logs dropped\n",
+		},
+		{
+			Config:  writer.AsyncConfig{Cap: 2, Overflow: writer.OverflowDropOldest},
+			Dropped: 3,
+			Logs:    "0 4 5 gxlog: 3 logs dropped\n",
+		},
+		{
+			Config: writer.AsyncConfig{
+				Cap:      2,
+				Overflow: writer.OverflowTimeout,
+				Timeout:  time.Millisecond,
+			},
+			Dropped: 3,
+			Logs:    "0 1 2 gxlog: 3 logs dropped\n",
+		},
+		{
+			Config:  writer.AsyncConfig{Cap: 2, Overflow: writer.OverflowDropBelow},
+			Levels:  []iface.Level{iface.Info, iface.Info, iface.Info, iface.Info},
+			Dropped: 1,
+			Logs:    "0 1 2 gxlog: 1 logs dropped\n",
+		},
+	}
+	for i, test := range tests {
+		wt := newBlockedWriter()
+		test.Config.NoticeInterval = time.Hour
+		async, err := writer.NewAsyncWithConfig(wt, test.Config)
+		if err != nil {
+			t.Fatalf("TestOverflow: %v", err)
+		}
+		async.Write([]byte("0 "), &iface.Record{})
+		<-wt.blocked
+		count := 5
+		if test.Levels != nil {
+			count = len(test.Levels) - 1
+		}
+		for j := 1; j <= count; j++ {
+			level := iface.Info
+			if test.Levels != nil {
+				level = test.Levels[j]
+			}
+			async.Write([]byte(fmt.Sprintf("%d ", j)), &iface.Record{Level: level})
+		}
+		if async.Dropped() != test.Dropped {
+			t.Errorf("TestOverflow: %d: dropped: %d", i, async.Dropped())
+		}
+		close(wt.release)
+		async.Close()
+		if logs := strings.Join(wt.logs, ""); logs != test.Logs {
+			t.Errorf("TestOverflow: %d: logs: %q", i, logs)
+		}
+	}
+
+	if _, err := writer.NewAsyncWithConfig(writer.Null(),
+		writer.AsyncConfig{Cap: -1}); err == nil {
+		t.Errorf("TestOverflow: negative Cap is NOT rejected")
+	}
+	if _, err := writer.NewAsyncWithConfig(writer.Null(),
+		writer.AsyncConfig{Overflow: writer.OverflowDropOldest}); err == nil {
+		t.Errorf("TestOverflow: 0 Cap with a drop policy is NOT rejected")
+	}
+}
+
+func TestNotice(t *testing.T) {
+	wt := newBlockedWriter()
+	async, err := writer.NewAsyncWithConfig(wt, writer.AsyncConfig{
+		Cap:            1,
+		Overflow:       writer.OverflowDropNewest,
+		NoticeInterval: time.Millisecond * 10,
+	})
+	if err != nil {
+		t.Fatalf("TestNotice: %v", err)
+	}
+	async.Write([]byte("0 "), &iface.Record{})
+	<-wt.blocked
+	async.Write([]byte("1 "), &iface.Record{})
+	async.Write([]byte("2 "), &iface.Record{})
+	close(wt.release)
+	// the notice is written by the ticker before Close
+	wt.wait(3)
+	async.Close()
+
+	logs := strings.Join(wt.logs, "")
+	if len(wt.logs) != 3 || !strings.HasPrefix(logs, "0 ") ||
+		!strings.Contains(logs, "1 ") ||
+		!strings.Contains(logs, "gxlog: 1 logs dropped\n") {
+		t.Errorf("TestNotice: logs: %q", logs)
+	}
+}
+
+func TestOrder(t *testing.T) {
+	wt := newBlockedWriter()
+	close(wt.release)
+	async := writer.NewAsync(wt, 4)
+	var expected []string
+	for i := 0; i < 1000; i++ {
+		log := fmt.Sprintf("%d ", i)
+		async.Write([]byte(log), &iface.Record{})
+		expected = append(expected, log)
+	}
+	async.Close()
+	if logs := strings.Join(wt.logs, ""); logs != strings.Join(expected, "") {
+		t.Errorf("TestOrder: logs are NOT in order: %q", logs)
+	}
+}
+
+func TestFlush(t *testing.T) {
+	wt := newBlockedWriter()
+	async := writer.NewAsync(wt, 4)
+	async.Write([]byte("0 "), &iface.Record{})
+	async.Write([]byte("1 "), &iface.Record{})
+	<-wt.blocked
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if err := async.Flush(ctx); err != context.DeadlineExceeded {
+		t.Errorf("TestFlush: Flush of a blocked writer: %v", err)
+	}
+	close(wt.release)
+	if err := async.Flush(context.Background()); err != nil {
+		t.Errorf("TestFlush: %v", err)
+	}
+	// no lock is needed because all logs written before Flush are output
+	if logs := strings.Join(wt.logs, ""); logs != "0 1 " {
+		t.Errorf("TestFlush: logs: %q", logs)
+	}
+
+	async.Close()
+	if err := async.Flush(context.Background()); err != writer.ErrClosed {
+		t.Errorf("TestFlush: Flush after Close: %v", err)
+	}
+}
+
+func TestFlushDropOldest(t *testing.T) {
+	wt := newBlockedWriter()
+	async, err := writer.NewAsyncWithConfig(wt, writer.AsyncConfig{
+		Cap:            2,
+		Overflow:       writer.OverflowDropOldest,
+		NoticeInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("TestFlushDropOldest: %v", err)
+	}
+	async.Write([]byte("0 "), &iface.Record{})
+	<-wt.blocked
+	async.Write([]byte("1 "), &iface.Record{})
+	chanFlush := make(chan error)
+	go func() {
+		chanFlush <- async.Flush(context.Background())
+	}()
+	for async.Len() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the 2nd Write takes the marker of Flush out of the channel and MUST NOT
+	// wait for the blocked writer
+	chanWrite := make(chan struct{})
+	go func() {
+		async.Write([]byte("2 "), &iface.Record{})
+		async.Write([]byte("3 "), &iface.Record{})
+		close(chanWrite)
+	}()
+	select {
+	case <-chanWrite:
+	case <-time.After(time.Second):
+		t.Fatalf("TestFlushDropOldest: Write blocks on the marker of Flush")
+	}
+	close(wt.release)
+	if err := <-chanFlush; err != nil {
+		t.Errorf("TestFlushDropOldest: %v", err)
+	}
+	async.Close()
+	if logs := strings.Join(wt.logs, ""); logs != "0 2 3 gxlog: 1 logs dropped\n" {
+		t.Errorf("TestFlushDropOldest: logs: %q", logs)
+	}
+}
+
+func TestCloseContext(t *testing.T) {
+	wt := newBlockedWriter()
+	async := writer.NewAsync(wt, 4)
+	async.Write([]byte("0 "), &iface.Record{})
+	async.Write([]byte("1 "), &iface.Record{})
+	<-wt.blocked
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		time.Sleep(time.Millisecond * 10)
+		close(wt.release)
+	}()
+	// waits until the log being output is done
+	if err := async.CloseContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("TestCloseContext: %v", err)
+	}
+	// no lock is needed because the goroutine of the Async has exited
+	if logs := strings.Join(wt.logs, ""); logs != "0 " {
+		t.Errorf("TestCloseContext: logs: %q", logs)
+	}
+}
+
+func TestWriteAfterClose(t *testing.T) {
+	var errs []error
+	async, err := writer.NewAsyncWithConfig(writer.Null(), writer.AsyncConfig{
+		ErrorHandler: func(bs []byte, record *iface.Record, err error) {
+			errs = append(errs, err)
+		},
+	})
+	if err != nil {
+		t.Fatalf("TestWriteAfterClose: %v", err)
+	}
+	async.Close()
+	async.Close()
+	async.Write([]byte("log"), &iface.Record{})
+	if len(errs) != 1 || errs[0] != writer.ErrClosed {
+		t.Errorf("TestWriteAfterClose: errors: %v", errs)
+	}
+}
@@ -17,6 +17,9 @@ const (
 	CFB BlockCipherMode = iota
 	CTR
 	OFB
+	// GCM frames log files into chunks authenticated with AES-GCM, so any
+	// truncation, reordering or tampering is detected when they are read.
+	GCM
 )
 
 const bufInitCap = 256
@@ -28,6 +31,9 @@ func newAESWriter(wt io.WriteCloser, key string, mode BlockCipherMode) (
 	if err != nil {
 		return wt, err
 	}
+	if mode == GCM {
+		return newGCMEncrypter(wt, keyBytes)
+	}
 	block, err := aes.NewCipher(keyBytes)
 	if err != nil {
 		return wt, err
@@ -90,3 +96,36 @@ func (enc *streamEncrypter) Write(bs []byte) (int, error) {
 
 	return enc.underlying.Write(buf)
 }
+
+func newStreamDecrypter(rd io.Reader, key string, mode BlockCipherMode) (
+	io.Reader, error) {
+
+	keyBytes, err := hex.DecodeString(key)
+	if err != nil {
+		return nil, err
+	}
+	if mode == GCM {
+		return newGCMDecrypter(rd, keyBytes)
+	}
+	block, err := aes.NewCipher(keyBytes)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rd, iv); err != nil {
+		return nil, err
+	}
+
+	var stream cipher.Stream
+	switch mode {
+	case CFB:
+		stream = cipher.NewCFBDecrypter(block, iv)
+	case CTR:
+		stream = cipher.NewCTR(block, iv)
+	case OFB:
+		stream = cipher.NewOFB(block, iv)
+	default:
+		return nil, errors.New("unhandled block cipher mode")
+	}
+	return &cipher.StreamReader{S: stream, R: rd}, nil
+}
@@ -0,0 +1,145 @@
+package file
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// appendFile opens the newest log file in the path that the logs at the tm
+// can be appended to, and returns its size. It returns a nil file if there is
+// none.
+func (writer *Writer) appendFile(path string, tm time.Time) (
+	*os.File, string, int64, error) {
+
+	files, err := writer.matchFiles(path, "")
+	if err != nil {
+		return nil, "", 0, err
+	}
+	date := fmt.Sprintf("%04d%02d%02d", tm.Year(), tm.Month(), tm.Day())
+	start, end := writer.period(tm)
+	var newest *logFile
+	for _, file := range files {
+		// the name of a log file of a previous process has another pid
+		if file.Compressed || file.Previous ||
+			file.Size >= writer.config.MaxFileSize ||
+			file.ModTime.Before(start) || !file.ModTime.Before(end) ||
+			writer.compressor.Pending(file.Pathname) {
+			continue
+		}
+		if writer.config.NoDirForDays &&
+			!strings.HasPrefix(file.Key, date) {
+			continue
+		}
+		if newest == nil || file.Key > newest.Key {
+			newest = file
+		}
+	}
+	if newest == nil || !writer.resumable(newest.Pathname) {
+		return nil, "", 0, nil
+	}
+	file, err := os.OpenFile(newest.Pathname, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	return file, newest.Pathname, sizeOf(file), nil
+}
+
+// sizeOf returns the size of the file on disk, even if it is gzipped.
+func sizeOf(file *os.File) int64 {
+	if info, err := file.Stat(); err == nil {
+		return info.Size()
+	}
+	return 0
+}
+
+// resumable reports whether the log file with the pathname is complete, so
+// that a new gzip member or GCM segment can be appended to it. A log file
+// left incomplete by a crash is NOT resumable because readers can NOT read
+// anything appended to it.
+func (writer *Writer) resumable(pathname string) bool {
+	file, err := os.Open(pathname)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	rd := bufio.NewReader(file)
+	magic, _ := rd.Peek(len(gcmMagic))
+	switch {
+	case writer.config.AESKey != "":
+		return completeSegments(rd, false)
+	case writer.config.PublicKey != "":
+		return completeSegments(rd, true)
+	case writer.config.GzipLevel != flate.NoCompression:
+		return completeGzip(rd)
+	}
+	// NOT to append plain logs to a gzipped or encrypted log file
+	return !(len(magic) >= 2 && magic[0] == 0x1f && magic[1] == 0x8b) &&
+		string(magic) != gcmMagic && string(magic) != pkeMagic
+}
+
+// completeGzip reports whether the rd consists of complete gzip members.
+func completeGzip(rd *bufio.Reader) bool {
+	if _, err := rd.Peek(1); err == io.EOF {
+		return true
+	}
+	gzipReader, err := gzip.NewReader(rd)
+	if err != nil {
+		return false
+	}
+	_, err = io.Copy(ioutil.Discard, gzipReader)
+	return err == nil
+}
+
+// completeSegments reports whether the rd consists of complete segments of
+// the GCM block mode, with the header of a PublicKey if pke is true. Only the
+// structure is checked, which does NOT need the key.
+func completeSegments(rd *bufio.Reader, pke bool) bool {
+	for {
+		if _, err := rd.Peek(1); err == io.EOF {
+			return true
+		}
+		if pke && !skipPKEHeader(rd) {
+			return false
+		}
+		header := make([]byte, len(gcmMagic)+gcmSaltSize)
+		if _, err := io.ReadFull(rd, header); err != nil ||
+			string(header[:len(gcmMagic)]) != gcmMagic {
+			return false
+		}
+		for final := false; !final; {
+			chunk := make([]byte, gcmHeaderSize)
+			if _, err := io.ReadFull(rd, chunk); err != nil {
+				return false
+			}
+			size := int64(binary.BigEndian.Uint32(chunk[9:]))
+			if n, _ := io.CopyN(ioutil.Discard, rd, size); n != size {
+				return false
+			}
+			final = chunk[8]&gcmFinal != 0
+		}
+	}
+}
+
+func skipPKEHeader(rd *bufio.Reader) bool {
+	prefix := make([]byte, len(pkeMagic)+1)
+	if _, err := io.ReadFull(rd, prefix); err != nil ||
+		string(prefix[:len(pkeMagic)]) != pkeMagic {
+		return false
+	}
+	rest := make([]byte, int(prefix[len(pkeMagic)])+3)
+	if _, err := io.ReadFull(rd, rest); err != nil {
+		return false
+	}
+	size := int64(binary.BigEndian.Uint16(rest[len(rest)-2:]))
+	n, _ := io.CopyN(ioutil.Discard, rd, size)
+	return n == size
+}
@@ -0,0 +1,171 @@
+package file
+
+import (
+	"bufio"
+	"io"
+	"time"
+
+	"github.com/gxlog/gxlog/iface"
+)
+
+// A SyncPolicy specifies when log files are synced to the storage by fsync.
+// The conditions of all the non-zero fields are combined with OR. The zero
+// value means never syncing and leaving it to the operating system.
+type SyncPolicy struct {
+	// EveryN specifies to sync after every N logs, e.g. 1 syncs after each
+	// log. It must NOT be negative.
+	EveryN int
+	// Interval specifies to sync when logs are written and the last sync is
+	// Interval ago. The logs NOT synced are synced by a timer at most Interval
+	// after the last sync even if no more log is written.
+	// It must NOT be negative.
+	Interval time.Duration
+	// Level specifies to sync after a log with a level NOT lower than Level.
+	Level iface.Level
+}
+
+func (policy *SyncPolicy) enabled() bool {
+	return policy.EveryN > 0 || policy.Interval > 0 || policy.Level != 0
+}
+
+type bufferedWriter struct {
+	underlying io.WriteCloser
+	buffer     *bufio.Writer
+}
+
+func newBufferedWriter(wt io.WriteCloser, size int) *bufferedWriter {
+	return &bufferedWriter{
+		underlying: wt,
+		buffer:     bufio.NewWriterSize(wt, size),
+	}
+}
+
+func (buffered *bufferedWriter) Write(bs []byte) (int, error) {
+	return buffered.buffer.Write(bs)
+}
+
+func (buffered *bufferedWriter) Flush() error {
+	return buffered.buffer.Flush()
+}
+
+func (buffered *bufferedWriter) Buffered() int {
+	return buffered.buffer.Buffered()
+}
+
+func (buffered *bufferedWriter) Close() error {
+	err := buffered.buffer.Flush()
+	if closeErr := buffered.underlying.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// afterWrite arms the flush timer if there are logs in the buffer, and syncs
+// the log file according to the SyncPolicy. If the logs are NOT synced, the
+// sync timer is armed with Sync.Interval.
+func (writer *Writer) afterWrite(record *iface.Record) error {
+	if writer.buffer != nil && writer.buffer.Buffered() > 0 &&
+		writer.flushTimer == nil {
+		writer.flushTimer = time.AfterFunc(writer.config.FlushInterval,
+			writer.flushByTimer)
+	}
+
+	policy := &writer.config.Sync
+	if !policy.enabled() {
+		return nil
+	}
+	writer.unsynced++
+	if (policy.EveryN > 0 && writer.unsynced >= policy.EveryN) ||
+		(policy.Interval > 0 && time.Since(writer.syncTime) >= policy.Interval) ||
+		(policy.Level != 0 && record.Level >= policy.Level) {
+		return writer.sync()
+	}
+	if policy.Interval > 0 && writer.syncTimer == nil {
+		writer.syncTimer = time.AfterFunc(
+			policy.Interval-time.Since(writer.syncTime), writer.syncByTimer)
+	}
+	return nil
+}
+
+// flush writes all the logs in the buffer to the log file.
+func (writer *Writer) flush() error {
+	if writer.flushTimer != nil {
+		writer.flushTimer.Stop()
+		writer.flushTimer = nil
+	}
+	if writer.buffer != nil {
+		return writer.buffer.Flush()
+	}
+	return nil
+}
+
+// sync flushes the buffer and then syncs the log file.
+func (writer *Writer) sync() error {
+	if err := writer.flush(); err != nil {
+		return err
+	}
+	if writer.syncTimer != nil {
+		writer.syncTimer.Stop()
+		writer.syncTimer = nil
+	}
+	writer.unsynced = 0
+	writer.syncTime = time.Now()
+	return writer.file.Sync()
+}
+
+func (writer *Writer) flushByTimer() {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	// the timer may have been stopped after it fired
+	if writer.flushTimer == nil {
+		return
+	}
+	writer.flushTimer = nil
+	if writer.buffer == nil || writer.buffer.Buffered() == 0 {
+		return
+	}
+	var err error
+	if writer.config.Sync.enabled() && writer.unsynced > 0 {
+		err = writer.sync()
+	} else {
+		err = writer.flush()
+	}
+	if err != nil {
+		// it will be reported with the next log
+		writer.pendingErr = err
+	}
+}
+
+func (writer *Writer) syncByTimer() {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	// the timer may have been stopped after it fired
+	if writer.syncTimer == nil {
+		return
+	}
+	writer.syncTimer = nil
+	if writer.file == nil || writer.unsynced == 0 {
+		return
+	}
+	if err := writer.sync(); err != nil {
+		// it will be reported with the next log
+		writer.pendingErr = err
+	}
+}
+
+// closeWriters flushes and closes the writers of the current log file. The
+// log file is synced first if the SyncPolicy is enabled.
+func (writer *Writer) closeWriters() error {
+	var err error
+	if writer.config.Sync.enabled() && writer.unsynced > 0 {
+		err = writer.sync()
+	} else {
+		err = writer.flush()
+	}
+	if closeErr := writer.writer.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,258 @@
+package file
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// The Compression defines the type of compression of rotated log files.
+type Compression int
+
+// All available compressions here.
+const (
+	CompressNone Compression = iota
+	// extension: .gz
+	CompressGzip
+	// extension: .zlib
+	CompressZlib
+	// extension: .deflate
+	CompressFlate
+)
+
+// compressedExtPattern matches the extensions of all the compressions.
+const compressedExtPattern = `((?:\.gz|\.zlib|\.deflate)?)`
+
+// the extension of the temporary files of compressions
+const tmpExt = ".tmp"
+
+// Ext returns the extension appended to the name of a compressed file.
+func (compression Compression) Ext() string {
+	switch compression {
+	case CompressGzip:
+		return ".gz"
+	case CompressZlib:
+		return ".zlib"
+	case CompressFlate:
+		return ".deflate"
+	}
+	return ""
+}
+
+// A compressor compresses rotated log files in background one by one.
+type compressor struct {
+	pending map[string]bool
+	lock    sync.Mutex
+	serial  sync.Mutex
+	done    sync.WaitGroup
+}
+
+type compressJob struct {
+	Pathname     string
+	Compression  Compression
+	Level        int
+	OnCompressed func(pathname string, err error)
+}
+
+// Add compresses the file of the job in background unless it is pending.
+func (comp *compressor) Add(job compressJob) {
+	comp.lock.Lock()
+	defer comp.lock.Unlock()
+
+	if comp.pending[job.Pathname] {
+		return
+	}
+	if comp.pending == nil {
+		comp.pending = make(map[string]bool)
+	}
+	comp.pending[job.Pathname] = true
+	comp.done.Add(1)
+	go comp.run(job)
+}
+
+// Pending returns whether the file is waiting for or under compression.
+func (comp *compressor) Pending(pathname string) bool {
+	comp.lock.Lock()
+	defer comp.lock.Unlock()
+
+	return comp.pending[pathname]
+}
+
+// Wait waits until all the compressions are done.
+func (comp *compressor) Wait() {
+	comp.done.Wait()
+}
+
+func (comp *compressor) run(job compressJob) {
+	defer comp.done.Done()
+
+	comp.serial.Lock()
+	pathname, err := compressFile(job.Pathname, job.Compression, job.Level)
+	comp.serial.Unlock()
+
+	comp.lock.Lock()
+	delete(comp.pending, job.Pathname)
+	comp.lock.Unlock()
+
+	if job.OnCompressed != nil {
+		job.OnCompressed(pathname, err)
+	}
+}
+
+// compressFile compresses the file into a temporary file, renames the
+// temporary file with the extension of the compression and then removes
+// the source file. It returns the pathname of the compressed file.
+// If it is interrupted, the file is left to be complete and it can be
+// compressed again.
+func compressFile(pathname string, compression Compression, level int) (
+	string, error) {
+
+	dst := pathname + compression.Ext()
+	tmp := dst + tmpExt
+	if err := compressTo(tmp, pathname, compression, level); err != nil {
+		os.Remove(tmp)
+		return dst, err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return dst, err
+	}
+	return dst, os.Remove(pathname)
+}
+
+func compressTo(dst, src string, compression Compression, level int) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	dstFile, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer dstFile.Close()
+
+	wt, err := newCompressWriter(dstFile, compression, level)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(wt, srcFile); err != nil {
+		return err
+	}
+	if err := wt.Close(); err != nil {
+		return err
+	}
+	if err := dstFile.Sync(); err != nil {
+		return err
+	}
+	return dstFile.Close()
+}
+
+func newCompressWriter(wt io.Writer, compression Compression, level int) (
+	io.WriteCloser, error) {
+
+	switch compression {
+	case CompressGzip:
+		return gzip.NewWriterLevel(wt, level)
+	case CompressZlib:
+		return zlib.NewWriterLevel(wt, level)
+	case CompressFlate:
+		return flate.NewWriter(wt, level)
+	}
+	return nil, errors.New("unhandled compression")
+}
+
+// compressRotated compresses the current log file after it is closed.
+func (writer *Writer) compressRotated() {
+	if writer.config.Compression == CompressNone {
+		return
+	}
+	writer.compressor.Add(writer.compressJob(writer.pathname))
+}
+
+// recoverCompression finishes the compressions interrupted, e.g. by a crash.
+// The log files matching the naming scheme and NOT compressed are compressed
+// except the current one. The source files left after being compressed and
+// the temporary files left are removed.
+func (writer *Writer) recoverCompression() error {
+	files, dirs, err := writer.listFiles()
+	if err != nil {
+		return err
+	}
+	if writer.config.NoDirForDays {
+		dirs = []string{writer.config.Path}
+	}
+	firstErr := writer.removeTemps(dirs)
+	ext := writer.config.Compression.Ext()
+	for _, file := range files {
+		if file.Compressed || file.Pathname == writer.pathname ||
+			writer.compressor.Pending(file.Pathname) {
+			continue
+		}
+		if _, err := os.Stat(file.Pathname + ext); err == nil {
+			if err := os.Remove(file.Pathname); err != nil && firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		writer.compressor.Add(writer.compressJob(file.Pathname))
+	}
+	return firstErr
+}
+
+// removeTemps removes the temporary files of the compressions interrupted in
+// the dirs, of any compression, except those of the compressions pending and
+// of the other processes still running.
+func (writer *Writer) removeTemps(dirs []string) error {
+	fileRegexp := regexp.MustCompile(writer.filenamePattern())
+	pidded := writer.config.Base == defaultBase()
+	running := make(map[string]bool)
+	var firstErr error
+	for _, dir := range dirs {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for _, info := range infos {
+			name := strings.TrimSuffix(info.Name(), tmpExt)
+			if name == info.Name() || !info.Mode().IsRegular() {
+				continue
+			}
+			matches := fileRegexp.FindStringSubmatch(name)
+			if matches == nil || matches[len(matches)-1] == "" ||
+				(pidded && otherProcess(matches[1], running)) {
+				continue
+			}
+			source := strings.TrimSuffix(name, matches[len(matches)-1])
+			if writer.compressor.Pending(filepath.Join(dir, source)) {
+				continue
+			}
+			err := os.Remove(filepath.Join(dir, info.Name()))
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+func (writer *Writer) compressJob(pathname string) compressJob {
+	return compressJob{
+		Pathname:     pathname,
+		Compression:  writer.config.Compression,
+		Level:        writer.config.CompressLevel,
+		OnCompressed: writer.config.OnCompressed,
+	}
+}
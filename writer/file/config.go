@@ -4,11 +4,13 @@ import (
 	"compress/flate"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
 	"time"
 
+	"github.com/gxlog/gxlog/iface"
 	"github.com/gxlog/gxlog/writer"
 )
 
@@ -42,6 +44,10 @@ const (
 	TimeDot
 	// hh:mm:ss.uuuuuu
 	TimeColon
+	// nnn, the sequence number of the log file in the day starting from 001.
+	// It has 3 digits at least and is NOT reused even if the log file with
+	// the number has been removed.
+	TimeSequence
 )
 
 // A Config is used to configure a file writer.
@@ -51,9 +57,26 @@ type Config struct {
 	// When it is modified in a file writer, a new log file will be created.
 	// If Path is not specified, "." is used.
 	Path string
+	// Filename is the fixed name of the log file in the Path, e.g. "app.log".
+	// If it is not empty, logs are always appended to the log file with the
+	// Filename, which is never rotated by the Writer, and the naming scheme,
+	// the rotation schedule and MaxFileSize are ignored. It is used together
+	// with an external log rotation tool, e.g. logrotate, which renames the
+	// log file and then calls Reopen or sends a signal handled by
+	// HandleSignals. A renamed log file is also reopened at the CheckInterval.
+	// It can NOT be used together with Symlink, Compression, GzipLevel,
+	// AESKey, PublicKey, MaxFiles, MaxAge or MaxTotalSize.
+	// When it is modified in a file writer, a new log file will be created.
+	Filename string
+	// ReopenOnSignal specifies to reopen the log file when a signal handled
+	// by HandleSignals is received.
+	ReopenOnSignal bool
 	// Base is the first segment of the name of log files.
 	// When it is modified in a file writer, a new log file will be created.
 	// If Base is not specified, filepath.Base(os.Args[0]).<pid> is used.
+	// With the default Base, the log files of the previous processes of the
+	// program, i.e. with any pid, also match the naming scheme, except those
+	// of the processes still running.
 	Base string
 	// Ext is the extension name of log files.
 	// When it is modified in a file writer, a new log file will be created.
@@ -71,18 +94,99 @@ type Config struct {
 	// When it is modified in a file writer, a new log file will be created.
 	// If TimeStyle is not specified, TimeCompact is used.
 	TimeStyle TimeStyle
+	// Location is the time zone used to name log files and day directories
+	// and to evaluate the rotation schedule.
+	// When it is modified in a file writer, a new log file will be created.
+	// If Location is not specified, time.Local is used.
+	Location *time.Location
+	// RotateEvery is the time interval to create a new log file, aligned to
+	// the midnight in the Location, e.g. time.Hour creates a new log file at
+	// each o'clock. A new log file is always created at the midnight.
+	// When it is modified in a file writer, a new log file will be created.
+	// If RotateEvery is not specified, log files are rotated daily.
+	// It must NOT be negative.
+	RotateEvery time.Duration
+	// RotateAt is the times of day, as the offsets from the midnight in the
+	// Location, to create a new log file, e.g. time.Hour * 6 for 06:00.
+	// It is combined with RotateEvery. Each of them MUST be in [0, 24h).
+	// The times of both are the wall clock times, even on the days of DST
+	// changes. A time skipped by DST is at the time of the change, and a time
+	// repeated is at the first one.
+	// When it is modified in a file writer, a new log file will be created.
+	RotateAt []time.Duration
 	// MaxFileSize is the max size of a log file BEFORE compression because
 	// (*gzip.Writer).Write returns the count of bytes before compression.
+	// With Compression, log files are NOT compressed until rotated out, so
+	// it is the size of a log file on disk before compressed.
 	// If MaxFileSize is not specified, (20 * 1024 * 1024) is used.
 	// It must NOT be negative.
 	MaxFileSize int64
+	// Append specifies to append to the newest log file in the current
+	// rotation period, if it is smaller than MaxFileSize, instead of creating
+	// a new one, e.g. when the process is restarted. A gzipped log file is
+	// appended with a new gzip member and an encrypted one in the GCM block
+	// mode or with a PublicKey is appended with a new segment of a new key.
+	// A gzipped or encrypted log file left incomplete by a crash is NOT
+	// appended to. It can NOT be used together with AESKey in the CFB, CTR or
+	// OFB block mode. Base should be specified, or the default one that
+	// contains the pid never matches the log files of a previous process.
+	Append bool
+	// Shared specifies that the log files are shared by multiple processes,
+	// or multiple Writers, with the same Path and Base. Logs are written in
+	// the append mode, and the size of the current log file is re-read from
+	// the disk before each log. A new log file is created by only one of them
+	// with an exclusive flock on the lock file "." + Base + ".lock" in the
+	// Path, and the others append to it. It implies Append. It can NOT be
+	// used together with BufferSize, Compression, GzipLevel, AESKey or
+	// PublicKey, with which logs of processes may be interleaved.
+	// It is supported only on Linux, macOS, the BSDs and illumos, which have
+	// flock.
+	// When it is modified in a file writer, a new log file will be created.
+	Shared bool
 	// CheckInterval is the time interval to check whether the current log file
-	// still exists. If not, a new log file will be created.
+	// still exists and is NOT replaced. If not, a new log file will be created.
 	// It is useful when you want to remove all log files and do not want to
 	// restart the process.
 	// If CheckInterval is not specified, (time.Second * 5) is used.
 	// For performance, it is better NOT to be less than 1s.
 	CheckInterval time.Duration
+	// BufferSize is the size of the buffer of log files. If it is not 0, logs
+	// are written to the buffer, which is flushed when it is full, FlushInterval
+	// after a log is written to it, when a new log file is created and when
+	// the Writer is closed.
+	// When it is modified in a file writer, a new log file will be created.
+	// If BufferSize is not specified, logs are NOT buffered.
+	// It must NOT be negative.
+	BufferSize int
+	// FlushInterval is the max time that logs stay in the buffer.
+	// If FlushInterval is not specified, time.Second is used.
+	// It must NOT be negative.
+	FlushInterval time.Duration
+	// Sync is the policy to sync log files to the storage. The buffer is
+	// flushed before each sync. Log files are also synced when they are closed
+	// if the policy is NOT the zero value.
+	Sync SyncPolicy
+	// Compression is the compression of log files rotated out. The current log
+	// file is written plainly and compressed in background after a new log
+	// file is created or the Writer is closed. A compressed file is renamed
+	// with the extension of the Compression appended. The log files matching
+	// the naming scheme and NOT compressed, e.g. left by a crash, are also
+	// compressed when the first log file is created, and the temporary files
+	// of compressions left are removed.
+	// It can NOT be used together with GzipLevel, AESKey or PublicKey.
+	// If Compression is not specified, CompressNone is used.
+	Compression Compression
+	// CompressLevel is the level of the Compression. It MUST be
+	// flate.DefaultCompression, flate.HuffmanOnly or any integer value between
+	// flate.BestSpeed and flate.BestCompression inclusive.
+	// If CompressLevel is not specified, flate.DefaultCompression is used.
+	CompressLevel int
+	// OnCompressed will be called with the pathname of the compressed file
+	// when a compression is done or fails if it is not nil. It is called in
+	// another goroutine. Do NOT call Close of the same Writer within it, and
+	// do NOT wait for any other method of the Writer to return within it, or
+	// it may deadlock.
+	OnCompressed func(pathname string, err error)
 	// GzipLevel is the level of gzip of log files. It will be handled by package
 	// compress/gzip. It MUST be flate.DefaultCompression, flate.NoCompression,
 	// flate.HuffmanOnly or any integer value between flate.BestSpeed and
@@ -96,10 +200,29 @@ type Config struct {
 	// an independent initialization vector.
 	// When it is modified in a file writer, a new log file will be created.
 	AESKey string
-	// BlockMode is the block mode of AES. It MUST be either CFB, CTR or OFB.
+	// BlockMode is the block mode of AES. It MUST be either CFB, CTR, OFB or
+	// GCM. GCM is the only one that authenticates log files.
 	// When it is modified in a file writer, a new log file will be created.
 	// If BlockMode is not specified, CFB is used.
 	BlockMode BlockCipherMode
+	// PublicKey is a PEM encoded PKIX public key of RSA or X25519. If it is not
+	// empty, each log file is encrypted in the GCM block mode with a random
+	// data key, which is wrapped with the PublicKey and stored at the beginning
+	// of the log file. Only the holder of the private key can read log files.
+	// It can NOT be used together with AESKey.
+	// When it is modified in a file writer, a new log file will be created.
+	PublicKey string
+	// KeyID is the identifier of the PublicKey stored in log files, with which
+	// readers choose the private key. It must NOT be longer than 255 bytes.
+	// When it is modified in a file writer, a new log file will be created.
+	KeyID string
+	// Preamble will be called each time a log file is opened, including when
+	// it is opened to append or reopened, if it is not nil. The returned
+	// preamble is written at the beginning of the log file, or after the logs
+	// already in it, the same as logs, e.g. gzipped and encrypted. It is used
+	// to record where the log file comes from, e.g. DefaultPreamble.
+	// Do NOT call any method of the Writer within it, or it may deadlock.
+	Preamble func(info *PreambleInfo) []byte
 	// ErrorHandler will be called when an error occurs if it is not nil.
 	ErrorHandler writer.ErrorHandler
 	// DirPerm represents the permission bits of created directories.
@@ -108,8 +231,77 @@ type Config struct {
 	// NoDirForDays specifies NOT to create a new directory each day.
 	// If NoDirForDays is true, the pattern of name of log files is
 	// <base><sep><date><sep><time><ext>, otherwise it is <base><sep><time><ext>.
+	// The <time> is a sequence number if TimeStyle is TimeSequence.
 	// When it is modified in a file writer, a new log file will be created.
 	NoDirForDays bool
+	// Symlink is the pathname of a symbolic link that always points to the
+	// current log file, e.g. "app.log". It is updated atomically each time
+	// a new log file is created. A relative Symlink is relative to the Path.
+	// When it is modified in a file writer, a new log file will be created.
+	// If Symlink is not specified, no symbolic link is created.
+	Symlink string
+	// MaxFiles is the max count of log files to keep. The oldest log files
+	// beyond it are removed.
+	// If MaxFiles is not specified, there is no limit on the count.
+	// It must NOT be negative.
+	MaxFiles int
+	// MaxAge is the max age of log files to keep, measured by the modification
+	// time. Log files older than it are removed.
+	// If MaxAge is not specified, there is no limit on the age.
+	// It must NOT be negative.
+	MaxAge time.Duration
+	// MaxTotalSize is the max total size of log files to keep. The oldest log
+	// files beyond it are removed.
+	// If MaxTotalSize is not specified, there is no limit on the total size.
+	// It must NOT be negative.
+	//
+	// The retention of MaxFiles, MaxAge and MaxTotalSize is applied each time
+	// a new log file is created. Only the files and day directories matching
+	// the current naming scheme are taken into account, and the current log
+	// file is never removed. Day directories left empty are removed. With the
+	// default Base, the log files of the previous processes of the program are
+	// taken into account, but NOT those of the other processes still running,
+	// which are kept until they exit.
+	MaxTotalSize int64
+	// MinFreeSpace is the min free space in bytes of the file system of the
+	// Path, checked by statfs on Linux, macOS, FreeBSD, DragonFly BSD and
+	// OpenBSD, or GetDiskFreeSpaceEx on Windows. If the free space is less
+	// than it, the disk space is low. It is NOT supported on the other
+	// platforms.
+	// If MinFreeSpace is not specified, the free space is NOT checked.
+	// It must NOT be negative.
+	MinFreeSpace int64
+	// MaxDirUsage is the max total size of all the files in the Path and its
+	// subdirectories. If the total size is larger than it, the disk space is
+	// low. Except the first time, the Path is walked at the CheckInterval in
+	// background, and logs are checked with the total size of the last walk
+	// in the meantime.
+	// If MaxDirUsage is not specified, the total size is NOT checked.
+	// It must NOT be negative.
+	MaxDirUsage int64
+	// SpacePolicy specifies what to do when the disk space is low. SpaceDrop
+	// drops logs with a level lower than SpaceLevel, SpaceRetention removes the
+	// oldest log files, except the current one, until the disk space is NOT
+	// low, and SpacePause drops all logs.
+	//
+	// The disk space is checked at the CheckInterval. A LowSpaceError is
+	// reported by the ErrorHandler once when the disk space becomes low, and
+	// a SpaceRecoveredError is reported once when it is NOT low any more,
+	// instead of reporting each log dropped. Only the first error of writing
+	// is reported while it is low, and the logs failed to write after it are
+	// counted as dropped.
+	// SpaceRetention can NOT be used together with Filename.
+	// If SpacePolicy is not specified, SpaceDrop is used.
+	SpacePolicy SpacePolicy
+	// SpaceLevel is the level of logs NOT to drop with SpaceDrop.
+	// If SpaceLevel is not specified, iface.Warn is used.
+	SpaceLevel iface.Level
+}
+
+// defaultBase returns the default Base, the name of the program followed by
+// the pid.
+func defaultBase() string {
+	return filepath.Base(os.Args[0]) + "." + strconv.Itoa(os.Getpid())
 }
 
 func (config *Config) setDefaults() {
@@ -117,7 +309,7 @@ func (config *Config) setDefaults() {
 		config.Path = "."
 	}
 	if config.Base == "" {
-		config.Base = filepath.Base(os.Args[0]) + "." + strconv.Itoa(os.Getpid())
+		config.Base = defaultBase()
 	}
 	if config.Ext == "" {
 		config.Ext = ".log"
@@ -131,6 +323,18 @@ func (config *Config) setDefaults() {
 	if config.CheckInterval == 0 {
 		config.CheckInterval = time.Second * 5
 	}
+	if config.Location == nil {
+		config.Location = time.Local
+	}
+	if config.FlushInterval == 0 {
+		config.FlushInterval = time.Second
+	}
+	if config.CompressLevel == 0 {
+		config.CompressLevel = flate.DefaultCompression
+	}
+	if config.SpaceLevel == 0 {
+		config.SpaceLevel = iface.Warn
+	}
 	if config.DirPerm == 0 {
 		config.DirPerm = 0700
 	}
@@ -143,17 +347,119 @@ func (config *Config) check() error {
 	if config.CheckInterval < 0 {
 		return errors.New("Config.CheckInterval must NOT be negative")
 	}
+	if config.RotateEvery < 0 {
+		return errors.New("Config.RotateEvery must NOT be negative")
+	}
+	for _, offset := range config.RotateAt {
+		if offset < 0 || offset >= time.Hour*24 {
+			return errors.New("Config.RotateAt must be in [0, 24h)")
+		}
+	}
+	if config.MaxFiles < 0 {
+		return errors.New("Config.MaxFiles must NOT be negative")
+	}
+	if config.MaxAge < 0 {
+		return errors.New("Config.MaxAge must NOT be negative")
+	}
+	if config.MaxTotalSize < 0 {
+		return errors.New("Config.MaxTotalSize must NOT be negative")
+	}
+	if config.MinFreeSpace < 0 {
+		return errors.New("Config.MinFreeSpace must NOT be negative")
+	}
+	if config.MinFreeSpace > 0 && !freeSpaceSupported {
+		return errors.New("Config.MinFreeSpace is NOT supported on the platform")
+	}
+	if config.MaxDirUsage < 0 {
+		return errors.New("Config.MaxDirUsage must NOT be negative")
+	}
+	if config.SpacePolicy < SpaceDrop || config.SpacePolicy > SpacePause {
+		return errors.New("Config.SpacePolicy is invalid")
+	}
+	if config.SpacePolicy == SpaceRetention && config.Filename != "" {
+		return errors.New("Config.SpacePolicy can NOT be SpaceRetention " +
+			"together with Config.Filename")
+	}
 	if config.GzipLevel < flate.HuffmanOnly ||
 		config.GzipLevel > flate.BestCompression {
 		return errors.New("Config.GzipLevel is invalid")
 	}
-	key, err := hex.DecodeString(config.AESKey)
-	if err != nil {
-		return errors.New("Config.AESKey is invalid")
+	if config.BufferSize < 0 {
+		return errors.New("Config.BufferSize must NOT be negative")
+	}
+	if config.FlushInterval < 0 {
+		return errors.New("Config.FlushInterval must NOT be negative")
+	}
+	if config.Sync.EveryN < 0 {
+		return errors.New("Config.Sync.EveryN must NOT be negative")
+	}
+	if config.Sync.Interval < 0 {
+		return errors.New("Config.Sync.Interval must NOT be negative")
+	}
+	if config.Compression < CompressNone || config.Compression > CompressFlate {
+		return errors.New("Config.Compression is invalid")
 	}
-	keyLen := len(key)
-	if keyLen != 0 && keyLen != 16 && keyLen != 24 && keyLen != 32 {
+	if config.CompressLevel < flate.HuffmanOnly ||
+		config.CompressLevel > flate.BestCompression {
+		return errors.New("Config.CompressLevel is invalid")
+	}
+	if config.Compression != CompressNone &&
+		(config.GzipLevel != flate.NoCompression || config.AESKey != "" ||
+			config.PublicKey != "") {
+		return errors.New("Config.Compression can NOT be used together with " +
+			"Config.GzipLevel, Config.AESKey or Config.PublicKey")
+	}
+	if config.BlockMode < CFB || config.BlockMode > GCM {
+		return errors.New("Config.BlockMode is invalid")
+	}
+	if !validAESKey(config.AESKey) {
 		return errors.New("Config.AESKey is invalid")
 	}
+	if config.PublicKey != "" {
+		if config.AESKey != "" {
+			return errors.New("Config.PublicKey can NOT be used together with " +
+				"Config.AESKey")
+		}
+		if _, err := parsePublicKey(config.PublicKey); err != nil {
+			return fmt.Errorf("Config.PublicKey is invalid: %v", err)
+		}
+	}
+	if config.Filename != "" && (config.Symlink != "" ||
+		config.Compression != CompressNone ||
+		config.GzipLevel != flate.NoCompression || config.AESKey != "" ||
+		config.PublicKey != "" || config.MaxFiles != 0 || config.MaxAge != 0 ||
+		config.MaxTotalSize != 0) {
+		return errors.New("Config.Filename can NOT be used together with " +
+			"Config.Symlink, Config.Compression, Config.GzipLevel, " +
+			"Config.AESKey, Config.PublicKey, Config.MaxFiles, Config.MaxAge " +
+			"or Config.MaxTotalSize")
+	}
+	if config.Shared && !sharedSupported {
+		return errors.New("Config.Shared is NOT supported on the platform")
+	}
+	if config.Shared && (config.BufferSize != 0 ||
+		config.Compression != CompressNone ||
+		config.GzipLevel != flate.NoCompression || config.AESKey != "" ||
+		config.PublicKey != "") {
+		return errors.New("Config.Shared can NOT be used together with " +
+			"Config.BufferSize, Config.Compression, Config.GzipLevel, " +
+			"Config.AESKey or Config.PublicKey")
+	}
+	if config.Append && config.AESKey != "" && config.BlockMode != GCM {
+		return errors.New("Config.Append can NOT be used together with " +
+			"Config.AESKey in the CFB, CTR or OFB block mode")
+	}
+	if len(config.KeyID) > 255 {
+		return errors.New("Config.KeyID must NOT be longer than 255 bytes")
+	}
 	return nil
 }
+
+func validAESKey(key string) bool {
+	keyBytes, err := hex.DecodeString(key)
+	if err != nil {
+		return false
+	}
+	keyLen := len(keyBytes)
+	return keyLen == 0 || keyLen == 16 || keyLen == 24 || keyLen == 32
+}
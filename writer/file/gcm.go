@@ -0,0 +1,215 @@
+package file
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// The layout of a log file in the GCM block mode:
+//
+//	file    = segment+
+//	segment = magic salt chunk*
+//	magic   = "GXLOGGCM"
+//	salt    = 32 random bytes, from which the key of the segment is derived
+//	chunk   = seq flags size ciphertext
+//
+// A new segment is appended each time the log file is reopened to append. The
+// seq is the 8-byte big-endian sequence number of the chunk in the segment
+// starting from 0, from which the nonce of the chunk is made. The flags is a
+// byte that is gcmFinal for the last chunk of the segment written when the log
+// file is closed. The size is the 4-byte big-endian size of the ciphertext.
+// The seq, flags and size are authenticated as the additional data of the
+// chunk.
+const (
+	gcmMagic      = "GXLOGGCM"
+	gcmSaltSize   = 32
+	gcmHeaderSize = 8 + 1 + 4
+	gcmMaxChunk   = 64 * 1024
+	gcmFinal      = 0x01
+)
+
+// ErrTruncated is returned by a Reader when a log file written in the GCM
+// block mode ends before its last chunk, e.g. it is truncated by a crash.
+// All the logs before the error have been verified.
+var ErrTruncated = errors.New("writer/file: the log file is truncated")
+
+// ErrCorrupted is returned by a Reader when a log file written in the GCM
+// block mode fails to be verified, e.g. it has been tampered with or its
+// chunks have been reordered.
+var ErrCorrupted = errors.New("writer/file: the log file is corrupted")
+
+type gcmEncrypter struct {
+	underlying io.WriteCloser
+	aead       cipher.AEAD
+	// the magic and salt NOT written yet
+	header []byte
+	seq    uint64
+	buf    []byte
+}
+
+func newGCMEncrypter(wt io.WriteCloser, key []byte) (io.WriteCloser, error) {
+	salt := make([]byte, gcmSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return wt, err
+	}
+	aead, err := newGCM(key, salt)
+	if err != nil {
+		return wt, err
+	}
+	return &gcmEncrypter{
+		underlying: wt,
+		aead:       aead,
+		header:     append([]byte(gcmMagic), salt...),
+		buf:        make([]byte, 0, bufInitCap),
+	}, nil
+}
+
+func (enc *gcmEncrypter) Write(bs []byte) (int, error) {
+	written := 0
+	for len(bs) > 0 {
+		size := len(bs)
+		if size > gcmMaxChunk {
+			size = gcmMaxChunk
+		}
+		if err := enc.writeChunk(bs[:size], 0); err != nil {
+			return written, err
+		}
+		written += size
+		bs = bs[size:]
+	}
+	return written, nil
+}
+
+func (enc *gcmEncrypter) Close() error {
+	if err := enc.writeChunk(nil, gcmFinal); err != nil {
+		enc.underlying.Close()
+		return err
+	}
+	return enc.underlying.Close()
+}
+
+func (enc *gcmEncrypter) writeChunk(bs []byte, flags byte) error {
+	if len(enc.header) > 0 {
+		n, err := enc.underlying.Write(enc.header)
+		enc.header = enc.header[n:]
+		if err != nil {
+			return err
+		}
+	}
+
+	buf := enc.buf[:gcmHeaderSize]
+	binary.BigEndian.PutUint64(buf, enc.seq)
+	buf[8] = flags
+	binary.BigEndian.PutUint32(buf[9:], uint32(len(bs)+enc.aead.Overhead()))
+	buf = enc.aead.Seal(buf, gcmNonce(enc.seq), bs, buf[:gcmHeaderSize])
+	enc.buf = buf[:0]
+	enc.seq++
+
+	_, err := enc.underlying.Write(buf)
+	return err
+}
+
+type gcmDecrypter struct {
+	underlying io.Reader
+	aead       cipher.AEAD
+	seq        uint64
+	final      bool
+	header     [gcmHeaderSize]byte
+	buf        []byte
+	// the plaintext NOT read yet
+	plain []byte
+}
+
+// newGCMDecrypter reads the magic and salt of the log file from the rd.
+// It returns io.EOF if the log file is empty.
+func newGCMDecrypter(rd io.Reader, key []byte) (io.Reader, error) {
+	header := make([]byte, len(gcmMagic)+gcmSaltSize)
+	if _, err := io.ReadFull(rd, header); err == io.ErrUnexpectedEOF {
+		return nil, ErrTruncated
+	} else if err != nil {
+		return nil, err
+	}
+	if string(header[:len(gcmMagic)]) != gcmMagic {
+		return nil, ErrCorrupted
+	}
+	aead, err := newGCM(key, header[len(gcmMagic):])
+	if err != nil {
+		return nil, err
+	}
+	return &gcmDecrypter{underlying: rd, aead: aead}, nil
+}
+
+func (dec *gcmDecrypter) Read(bs []byte) (int, error) {
+	for len(dec.plain) == 0 {
+		if dec.final {
+			return 0, io.EOF
+		}
+		if err := dec.readChunk(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(bs, dec.plain)
+	dec.plain = dec.plain[n:]
+	return n, nil
+}
+
+func (dec *gcmDecrypter) readChunk() error {
+	header := dec.header[:]
+	if _, err := io.ReadFull(dec.underlying, header); err != nil {
+		return truncatedError(err)
+	}
+	seq := binary.BigEndian.Uint64(header)
+	size := int(binary.BigEndian.Uint32(header[9:]))
+	if seq != dec.seq || size > gcmMaxChunk+dec.aead.Overhead() {
+		return ErrCorrupted
+	}
+	if cap(dec.buf) < size {
+		dec.buf = make([]byte, size)
+	}
+	ciphertext := dec.buf[:size]
+	if _, err := io.ReadFull(dec.underlying, ciphertext); err != nil {
+		return truncatedError(err)
+	}
+	plain, err := dec.aead.Open(ciphertext[:0], gcmNonce(seq), ciphertext, header)
+	if err != nil {
+		return ErrCorrupted
+	}
+	dec.seq++
+	dec.plain = plain
+	if header[8]&gcmFinal != 0 {
+		dec.final = true
+	}
+	return nil
+}
+
+// newGCM creates the AEAD with the key derived from the key and the salt.
+// The derived key has the same size with the key.
+func newGCM(key, salt []byte) (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(gcmMagic))
+	mac.Write(salt)
+	block, err := aes.NewCipher(mac.Sum(nil)[:len(key)])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func gcmNonce(seq uint64) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[4:], seq)
+	return nonce
+}
+
+func truncatedError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrTruncated
+	}
+	return err
+}
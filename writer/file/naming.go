@@ -0,0 +1,146 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const seqKeyWidth = 20
+
+// nextSequence returns the next sequence number of log files of the day of
+// the tm in the path.
+func (writer *Writer) nextSequence(path string, tm time.Time) (int, error) {
+	files, err := writer.matchFiles(path, "")
+	if err != nil {
+		return 0, err
+	}
+	date := fmt.Sprintf("%04d%02d%02d", tm.Year(), tm.Month(), tm.Day())
+	seq := 0
+	for _, file := range files {
+		if writer.config.NoDirForDays && !strings.HasPrefix(file.Key, date) {
+			continue
+		}
+		n, err := strconv.Atoi(file.Key[len(file.Key)-seqKeyWidth:])
+		if err == nil && n > seq {
+			seq = n
+		}
+	}
+	return seq + 1, nil
+}
+
+// updateSymlink points the symbolic link to the current log file atomically
+// by renaming a new symbolic link to it.
+func (writer *Writer) updateSymlink() error {
+	link := writer.config.Symlink
+	if !filepath.IsAbs(link) {
+		link = filepath.Join(writer.config.Path, link)
+	}
+	if err := os.MkdirAll(filepath.Dir(link), writer.config.DirPerm); err != nil {
+		return err
+	}
+	target, err := filepath.Rel(filepath.Dir(link), writer.pathname)
+	if err != nil {
+		target = writer.pathname
+	}
+	tmp := link + ".tmp" + strconv.Itoa(os.Getpid())
+	os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// otherProcess reports whether the pid in the name of a log file is of another
+// process still running. The results are cached in the running.
+func otherProcess(pid string, running map[string]bool) bool {
+	if pid == strconv.Itoa(os.Getpid()) {
+		return false
+	}
+	alive, ok := running[pid]
+	if !ok {
+		n, _ := strconv.Atoi(pid)
+		alive = processAlive(n)
+		running[pid] = alive
+	}
+	return alive
+}
+
+// fileKey returns the key of a log file with the submatches of the date and
+// time in its name.
+func (writer *Writer) fileKey(submatches []string) string {
+	if writer.config.TimeStyle == TimeSequence {
+		// pad the sequence number to sort numerically
+		last := len(submatches) - 1
+		seq := submatches[last]
+		if len(seq) < seqKeyWidth {
+			seq = strings.Repeat("0", seqKeyWidth-len(seq)) + seq
+		}
+		return strings.Join(submatches[:last], "") + seq
+	}
+	return strings.Join(submatches, "")
+}
+
+// filenamePattern returns the regular expression matching the names of log
+// files, of which the submatches are the pid if the Base is the default one,
+// the digits of the date and time and the extension of the compression, which
+// is empty if NOT compressed.
+func (writer *Writer) filenamePattern() string {
+	sep := regexp.QuoteMeta(writer.config.Separator)
+	pattern := "^"
+	if writer.config.Base == defaultBase() {
+		// to match the log files of the previous processes of the program
+		pattern += regexp.QuoteMeta(filepath.Base(os.Args[0])) + `\.(\d+)` + sep
+	} else if writer.config.Base != "" {
+		pattern += regexp.QuoteMeta(writer.config.Base) + sep
+	}
+	if writer.config.NoDirForDays {
+		pattern += datePattern(writer.config.DateStyle) + sep
+	}
+	pattern += timePattern(writer.config.TimeStyle)
+	pattern += regexp.QuoteMeta(writer.config.Ext)
+	return pattern + compressedExtPattern + "$"
+}
+
+// datePattern returns the regular expression of the date formatted by
+// formatDate.
+func datePattern(style DateStyle) string {
+	sep := ""
+	switch style {
+	case DateDash:
+		sep = "-"
+	case DateUnderscore:
+		sep = "_"
+	case DateDot:
+		sep = `\.`
+	}
+	return `(\d{4})` + sep + `(\d{2})` + sep + `(\d{2})`
+}
+
+// timePattern returns the regular expression of the time formatted by
+// formatTime, or of the sequence number in the TimeSequence style.
+func timePattern(style TimeStyle) string {
+	if style == TimeSequence {
+		return `(\d{3,})`
+	}
+	sep, last := "", `\.`
+	switch style {
+	case TimeDash:
+		sep, last = "-", "-"
+	case TimeUnderscore:
+		sep, last = "_", "_"
+	case TimeDot:
+		sep, last = `\.`, `\.`
+	case TimeColon:
+		sep = ":"
+	}
+	return `(\d{2})` + sep + `(\d{2})` + sep + `(\d{2})` + last + `(\d{6})`
+}
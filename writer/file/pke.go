@@ -0,0 +1,252 @@
+package file
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The layout of a log file encrypted with a public key:
+//
+//	file    = segment+
+//	segment = magic keyID alg wrapped gcmSegment
+//	magic   = "GXLOGPKE"
+//	keyID   = 1-byte size and then the KeyID
+//	alg     = pkeRSAOAEP or pkeX25519
+//	wrapped = 2-byte big-endian size and then the wrapped data key
+//
+// The gcmSegment is the same with a segment of a log file written in the GCM
+// block mode with the data key, which is random and 256 bits. With pkeRSAOAEP,
+// the data key is wrapped with RSA-OAEP with SHA-256. With pkeX25519, the
+// wrapped data key is an ephemeral X25519 public key followed by the data key
+// sealed with AES-GCM, of which the key is derived by HKDF-SHA-256 from the
+// shared secret.
+const (
+	pkeMagic    = "GXLOGPKE"
+	pkeRSAOAEP  = 1
+	pkeX25519   = 2
+	pkeKeySize  = 32
+	pkeHKDFInfo = "gxlog pke x25519"
+)
+
+func newHybridEncrypter(wt io.WriteCloser, publicKey, keyID string) (
+	io.WriteCloser, error) {
+
+	pub, err := parsePublicKey(publicKey)
+	if err != nil {
+		return wt, err
+	}
+	dataKey := make([]byte, pkeKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return wt, err
+	}
+	alg, wrapped, err := wrapKey(pub, dataKey)
+	if err != nil {
+		return wt, err
+	}
+	wt, err = newGCMEncrypter(wt, dataKey)
+	if err != nil {
+		return wt, err
+	}
+
+	header := append([]byte(pkeMagic), byte(len(keyID)))
+	header = append(header, keyID...)
+	header = append(header, alg, 0, 0)
+	binary.BigEndian.PutUint16(header[len(header)-2:], uint16(len(wrapped)))
+	header = append(header, wrapped...)
+	enc := wt.(*gcmEncrypter)
+	enc.header = append(header, enc.header...)
+	return enc, nil
+}
+
+// newHybridDecrypter reads the header of the log file from the rd and
+// unwraps the data key with the private key of the KeyID in the header.
+// It returns io.EOF if the log file is empty.
+func newHybridDecrypter(rd io.Reader, privateKeys map[string]string) (
+	io.Reader, error) {
+
+	prefix := make([]byte, len(pkeMagic)+1)
+	if _, err := io.ReadFull(rd, prefix); err == io.ErrUnexpectedEOF {
+		return nil, ErrTruncated
+	} else if err != nil {
+		return nil, err
+	}
+	if string(prefix[:len(pkeMagic)]) != pkeMagic {
+		return nil, ErrCorrupted
+	}
+	rest := make([]byte, int(prefix[len(pkeMagic)])+3)
+	if _, err := io.ReadFull(rd, rest); err != nil {
+		return nil, truncatedError(err)
+	}
+	keyID := string(rest[:len(rest)-3])
+	alg := rest[len(rest)-3]
+	wrapped := make([]byte, binary.BigEndian.Uint16(rest[len(rest)-2:]))
+	if _, err := io.ReadFull(rd, wrapped); err != nil {
+		return nil, truncatedError(err)
+	}
+
+	privateKey, ok := privateKeys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("no private key with the KeyID: %q", keyID)
+	}
+	priv, err := parsePrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := unwrapKey(priv, alg, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	dec, err := newGCMDecrypter(rd, dataKey)
+	if err == io.EOF {
+		// the data key has been written but no log
+		return nil, ErrTruncated
+	}
+	return dec, err
+}
+
+func wrapKey(pub interface{}, dataKey []byte) (byte, []byte, error) {
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		wrapped, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, dataKey, nil)
+		return pkeRSAOAEP, wrapped, err
+	case *ecdh.PublicKey:
+		ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return 0, nil, err
+		}
+		aead, err := x25519KEK(ephemeral, pub, ephemeral.PublicKey())
+		if err != nil {
+			return 0, nil, err
+		}
+		wrapped := ephemeral.PublicKey().Bytes()
+		nonce := make([]byte, aead.NonceSize())
+		return pkeX25519, aead.Seal(wrapped, nonce, dataKey, nil), nil
+	}
+	return 0, nil, errors.New("unsupported public key")
+}
+
+func unwrapKey(priv interface{}, alg byte, wrapped []byte) ([]byte, error) {
+	switch priv := priv.(type) {
+	case *rsa.PrivateKey:
+		if alg != pkeRSAOAEP {
+			break
+		}
+		dataKey, err := rsa.DecryptOAEP(sha256.New(), nil, priv, wrapped, nil)
+		if err != nil {
+			return nil, ErrCorrupted
+		}
+		return dataKey, nil
+	case *ecdh.PrivateKey:
+		if alg != pkeX25519 || len(wrapped) < 32 {
+			break
+		}
+		ephemeral, err := ecdh.X25519().NewPublicKey(wrapped[:32])
+		if err != nil {
+			return nil, ErrCorrupted
+		}
+		aead, err := x25519KEK(priv, ephemeral, ephemeral)
+		if err != nil {
+			return nil, ErrCorrupted
+		}
+		nonce := make([]byte, aead.NonceSize())
+		dataKey, err := aead.Open(nil, nonce, wrapped[32:], nil)
+		if err != nil {
+			return nil, ErrCorrupted
+		}
+		return dataKey, nil
+	}
+	return nil, errors.New("the private key does NOT match the log file")
+}
+
+// x25519KEK returns the AEAD to wrap the data key with the shared secret of
+// the priv and the peer. The ephemeral public key is used as the salt.
+func x25519KEK(priv *ecdh.PrivateKey, peer, ephemeral *ecdh.PublicKey) (
+	cipher.AEAD, error) {
+
+	secret, err := priv.ECDH(peer)
+	if err != nil {
+		return nil, err
+	}
+	kek := hkdfSHA256(secret, ephemeral.Bytes(), pkeHKDFInfo, 32)
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// hkdfSHA256 derives a key of the size from the secret with HKDF-SHA256 as
+// RFC 5869. It is implemented here because the package crypto/hkdf needs
+// Go 1.24. The size must NOT be greater than 255 * sha256.Size.
+func hkdfSHA256(secret, salt []byte, info string, size int) []byte {
+	extractor := hmac.New(sha256.New, salt)
+	extractor.Write(secret)
+	expander := hmac.New(sha256.New, extractor.Sum(nil))
+	var key, block []byte
+	for counter := byte(1); len(key) < size; counter++ {
+		expander.Reset()
+		expander.Write(block)
+		expander.Write([]byte(info))
+		expander.Write([]byte{counter})
+		block = expander.Sum(nil)
+		key = append(key, block...)
+	}
+	return key[:size]
+}
+
+// parsePublicKey parses a PEM encoded PKIX public key of RSA or X25519.
+func parsePublicKey(publicKey string) (interface{}, error) {
+	block, _ := pem.Decode([]byte(publicKey))
+	if block == nil {
+		return nil, errors.New("no PEM encoded public key")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		return pub, nil
+	case *ecdh.PublicKey:
+		if pub.Curve() == ecdh.X25519() {
+			return pub, nil
+		}
+	}
+	return nil, errors.New("the public key is neither RSA nor X25519")
+}
+
+// parsePrivateKey parses a PEM encoded PKCS #8 private key of RSA or X25519,
+// or a PKCS #1 private key of RSA.
+func parsePrivateKey(privateKey string) (interface{}, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return nil, errors.New("no PEM encoded private key")
+	}
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	priv, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch priv := priv.(type) {
+	case *rsa.PrivateKey:
+		return priv, nil
+	case *ecdh.PrivateKey:
+		if priv.Curve() == ecdh.X25519() {
+			return priv, nil
+		}
+	}
+	return nil, errors.New("the private key is neither RSA nor X25519")
+}
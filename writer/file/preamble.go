@@ -0,0 +1,138 @@
+package file
+
+import (
+	"compress/flate"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// the time when the process started, approximately
+var startTime = time.Now()
+
+// A PreambleInfo is the information about a log file and the process writing
+// it, passed to the Preamble of a Config.
+type PreambleInfo struct {
+	Pathname string
+	// the time when the log file is opened
+	Time time.Time
+	// whether the log file is opened to append, with logs already in it
+	Appended bool
+
+	Hostname string
+	Pid      int
+	// the name of the binary, i.e. os.Args[0]
+	Binary string
+	// nil if the binary is NOT built with module support
+	BuildInfo *debug.BuildInfo
+	StartTime time.Time
+
+	// flate.NoCompression if the log file is NOT gzipped
+	GzipLevel int
+	// empty, "aes-cfb", "aes-ctr", "aes-ofb", "aes-gcm" or "public-key"
+	Encryption string
+	// the KeyID of the PublicKey
+	KeyID string
+}
+
+// DefaultPreamble returns the info formatted as lines beginning with "# ".
+// To add the header of the formatter, e.g. a text.Formatter, use a Preamble
+// like:
+//
+//	func(info *file.PreambleInfo) []byte {
+//		preamble := file.DefaultPreamble(info)
+//		return append(preamble, "# header: "+formatter.Header()+"\n"...)
+//	}
+func DefaultPreamble(info *PreambleInfo) []byte {
+	var lines []string
+	add := func(key, value string) {
+		lines = append(lines, "# "+key+": "+value+"\n")
+	}
+	opened := info.Time.Format(time.RFC3339Nano)
+	if info.Appended {
+		opened += " (appended)"
+	}
+	add("log file", info.Pathname)
+	add("opened", opened)
+	add("hostname", info.Hostname)
+	add("pid", fmt.Sprint(info.Pid))
+	add("binary", info.Binary)
+	if info.BuildInfo != nil {
+		add("build", formatBuildInfo(info.BuildInfo))
+	}
+	add("started", info.StartTime.Format(time.RFC3339Nano))
+	if info.GzipLevel != flate.NoCompression {
+		add("gzip level", fmt.Sprint(info.GzipLevel))
+	}
+	if info.Encryption != "" {
+		add("encryption", info.Encryption)
+	}
+	if info.KeyID != "" {
+		add("key id", info.KeyID)
+	}
+	return []byte(strings.Join(lines, ""))
+}
+
+// writePreamble writes the preamble of the current log file if the Preamble
+// of the config is not nil.
+func (writer *Writer) writePreamble() error {
+	if writer.config.Preamble == nil {
+		return nil
+	}
+	hostname, _ := os.Hostname()
+	buildInfo, _ := debug.ReadBuildInfo()
+	preamble := writer.config.Preamble(&PreambleInfo{
+		Pathname:   writer.pathname,
+		Time:       time.Now().In(writer.config.Location),
+		Appended:   writer.fileSize > 0,
+		Hostname:   hostname,
+		Pid:        os.Getpid(),
+		Binary:     os.Args[0],
+		BuildInfo:  buildInfo,
+		StartTime:  startTime.In(writer.config.Location),
+		GzipLevel:  writer.config.GzipLevel,
+		Encryption: writer.encryption(),
+		KeyID:      writer.config.KeyID,
+	})
+	if len(preamble) == 0 {
+		return nil
+	}
+	n, err := writer.writer.Write(preamble)
+	writer.fileSize += int64(n)
+	return err
+}
+
+func (writer *Writer) encryption() string {
+	if writer.config.PublicKey != "" {
+		return "public-key"
+	}
+	if writer.config.AESKey == "" {
+		return ""
+	}
+	switch writer.config.BlockMode {
+	case CTR:
+		return "aes-ctr"
+	case OFB:
+		return "aes-ofb"
+	case GCM:
+		return "aes-gcm"
+	}
+	return "aes-cfb"
+}
+
+func formatBuildInfo(info *debug.BuildInfo) string {
+	elements := []string{info.Main.Path}
+	if info.Main.Version != "" {
+		elements = append(elements, info.Main.Version)
+	}
+	elements = append(elements, info.GoVersion)
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision", "vcs.time", "vcs.modified":
+			elements = append(elements, setting.Key+"="+setting.Value)
+		}
+	}
+	return strings.Join(elements, " ")
+}
@@ -0,0 +1,19 @@
+//go:build !unix
+
+package file
+
+import "os"
+
+// processAlive reports whether the process with the pid is running. It
+// reports true if it is unknown on the platform.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
@@ -0,0 +1,14 @@
+//go:build unix
+
+package file
+
+import "syscall"
+
+// processAlive reports whether the process with the pid is running.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
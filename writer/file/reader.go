@@ -0,0 +1,331 @@
+package file
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// A ReaderConfig is used to configure a Reader.
+type ReaderConfig struct {
+	// AESKey is the hexadecimal encoded AES key with which log files were
+	// written. If it is empty, log files are NOT decrypted.
+	AESKey string
+	// BlockMode is the block mode of AES with which log files were written.
+	// If BlockMode is not specified, CFB is used.
+	BlockMode BlockCipherMode
+	// PrivateKeys maps the KeyIDs of log files written with a PublicKey to
+	// the PEM encoded private keys, in PKCS #8 or in PKCS #1 for RSA. If it is
+	// not empty, log files are decrypted with the private key of their KeyID.
+	// It can NOT be used together with AESKey.
+	PrivateKeys map[string]string
+}
+
+func (config *ReaderConfig) check() error {
+	if !validAESKey(config.AESKey) {
+		return errors.New("ReaderConfig.AESKey is invalid")
+	}
+	if config.AESKey != "" && len(config.PrivateKeys) > 0 {
+		return errors.New("ReaderConfig.PrivateKeys can NOT be used together " +
+			"with ReaderConfig.AESKey")
+	}
+	for keyID, privateKey := range config.PrivateKeys {
+		if _, err := parsePrivateKey(privateKey); err != nil {
+			return fmt.Errorf("ReaderConfig.PrivateKeys[%q] is invalid: %v",
+				keyID, err)
+		}
+	}
+	return nil
+}
+
+// A Reader reads the plain logs of a log file written by a Writer. It decrypts
+// and decompresses the log file as needed.
+//
+// A log file truncated by a crash is read up to where it is truncated without
+// any error. The last log read from it may be incomplete. Except that in the
+// GCM block mode, ErrTruncated is returned after all the verified logs have
+// been read, and ErrCorrupted is returned if it fails to be verified. So it is
+// with log files written with a PublicKey. All the segments of log files
+// appended to with Append are read.
+//
+// A Reader MUST be created with NewReader or OpenReader.
+type Reader struct {
+	reader io.Reader
+	closer io.Closer
+}
+
+// NewReader creates a new Reader that reads a log file from the rd.
+// The log file is decrypted with the AESKey or the PrivateKeys of the config
+// if either is not empty, and then decompressed if it is gzipped, either with
+// GzipLevel or with CompressGzip. Use OpenReader for log files compressed with CompressZlib or
+// CompressFlate.
+func NewReader(rd io.Reader, config ReaderConfig) (*Reader, error) {
+	if err := config.check(); err != nil {
+		return nil, fmt.Errorf("writer/file.NewReader: %v", err)
+	}
+	reader, err := newReader(rd, CompressNone, config)
+	if err != nil {
+		return nil, fmt.Errorf("writer/file.NewReader: %v", err)
+	}
+	return reader, nil
+}
+
+// OpenReader opens the log file with the pathname and creates a new Reader
+// that reads it. It does the same with NewReader except that log files
+// compressed with any Compression are detected by the extension.
+func OpenReader(pathname string, config ReaderConfig) (*Reader, error) {
+	if err := config.check(); err != nil {
+		return nil, fmt.Errorf("writer/file.OpenReader: %v", err)
+	}
+	file, err := os.Open(pathname)
+	if err != nil {
+		return nil, fmt.Errorf("writer/file.OpenReader: %v", err)
+	}
+	reader, err := newReader(file, compressionOf(pathname), config)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("writer/file.OpenReader: %v", err)
+	}
+	reader.closer = file
+	return reader, nil
+}
+
+// Read implements the interface io.Reader.
+func (reader *Reader) Read(bs []byte) (int, error) {
+	n, err := reader.reader.Read(bs)
+	if err == io.ErrUnexpectedEOF {
+		// truncated by a crash
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Close closes the log file if it is opened by OpenReader.
+func (reader *Reader) Close() error {
+	if reader.closer != nil {
+		return reader.closer.Close()
+	}
+	return nil
+}
+
+// ListFiles returns the pathnames of all the log files in the dir and its
+// subdirectories in time order. The order is that of the dates and times, or
+// sequence numbers, in the names of day directories and log files, regardless
+// of the Base, e.g. the default one with the pid of each process. Files
+// without any date and time, e.g. with the Filename, are at the end. Files
+// with the same date and time, or without any, are in the order of their
+// pathnames, in which the numbers are compared numerically.
+// Temporary files of compression, lock files of Shared and symbolic links are
+// excluded.
+func ListFiles(dir string) ([]string, error) {
+	var pathnames []string
+	err := filepath.Walk(dir, func(pathname string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() && !strings.HasSuffix(pathname, tmpExt) &&
+			!strings.HasSuffix(pathname, sharedLockExt) {
+			pathnames = append(pathnames, pathname)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("writer/file.ListFiles: %v", err)
+	}
+	keys := make(map[string]string, len(pathnames))
+	for _, pathname := range pathnames {
+		keys[pathname] = timeKey(pathname)
+	}
+	sort.SliceStable(pathnames, func(i, j int) bool {
+		left, right := pathnames[i], pathnames[j]
+		if keys[left] == "" || keys[right] == "" {
+			if keys[left] != keys[right] {
+				return keys[right] == ""
+			}
+		} else if keys[left] != keys[right] {
+			return naturalLess(keys[left], keys[right])
+		}
+		return naturalLess(filepath.ToSlash(left), filepath.ToSlash(right))
+	})
+	return pathnames, nil
+}
+
+var (
+	// the name of a day directory in any DateStyle
+	dayDirRegexp = regexp.MustCompile(`^(\d{4})[-_.]?(\d{2})[-_.]?(\d{2})$`)
+	// the optional date and the time in any TimeStyle, or the sequence number,
+	// at the end of the name of a log file, followed by the extensions that do
+	// NOT begin with a digit
+	fileTimeRegexp = regexp.MustCompile(`(?:(\d{4})[-_.]?(\d{2})[-_.]?(\d{2})[-_.])?` +
+		`(?:(\d{2})[-_:.]?(\d{2})[-_:.]?(\d{2})[-_.](\d{6})|(\d{3,}))` +
+		`(?:\.[^.\d][^.]*)*$`)
+)
+
+// timeKey returns the digits of the date and time, or sequence number, in the
+// name of the day directory and the name of the log file with the pathname.
+// It returns an empty string if the name does NOT match any naming scheme.
+func timeKey(pathname string) string {
+	matches := fileTimeRegexp.FindStringSubmatch(filepath.Base(pathname))
+	if matches == nil {
+		return ""
+	}
+	date := strings.Join(matches[1:4], "")
+	if date == "" {
+		dayDir := dayDirRegexp.FindStringSubmatch(filepath.Base(filepath.Dir(pathname)))
+		if dayDir == nil {
+			return ""
+		}
+		date = strings.Join(dayDir[1:], "")
+	}
+	return date + " " + strings.Join(matches[4:], "")
+}
+
+func newReader(rd io.Reader, compression Compression, config ReaderConfig) (
+	*Reader, error) {
+
+	if config.AESKey != "" || len(config.PrivateKeys) > 0 {
+		open := func(rd io.Reader) (io.Reader, error) {
+			if config.AESKey != "" {
+				return newStreamDecrypter(rd, config.AESKey, config.BlockMode)
+			}
+			return newHybridDecrypter(rd, config.PrivateKeys)
+		}
+		var err error
+		if config.AESKey != "" && config.BlockMode != GCM {
+			rd, err = open(rd)
+		} else {
+			// appended log files consist of segments
+			rd, err = newSegmentReader(rd, open)
+		}
+		if isTruncated(err) {
+			return &Reader{reader: strings.NewReader("")}, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	buffered := bufio.NewReader(rd)
+	var reader io.Reader
+	var err error
+	switch compression {
+	case CompressZlib:
+		reader, err = zlib.NewReader(buffered)
+	case CompressFlate:
+		reader = flate.NewReader(buffered)
+	default:
+		magic, _ := buffered.Peek(2)
+		if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+			reader, err = gzip.NewReader(buffered)
+		} else {
+			reader = buffered
+		}
+	}
+	if isTruncated(err) {
+		return &Reader{reader: strings.NewReader("")}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &Reader{reader: reader}, nil
+}
+
+// A segmentReader reads the segments of a log file one by one, each of which
+// is decrypted by the reader created by the open.
+type segmentReader struct {
+	underlying *bufio.Reader
+	open       func(io.Reader) (io.Reader, error)
+	current    io.Reader
+}
+
+func newSegmentReader(rd io.Reader, open func(io.Reader) (io.Reader, error)) (
+	io.Reader, error) {
+
+	underlying := bufio.NewReader(rd)
+	current, err := open(underlying)
+	if err != nil {
+		return nil, err
+	}
+	return &segmentReader{
+		underlying: underlying,
+		open:       open,
+		current:    current,
+	}, nil
+}
+
+func (reader *segmentReader) Read(bs []byte) (int, error) {
+	for {
+		n, err := reader.current.Read(bs)
+		if n > 0 || err != io.EOF {
+			return n, err
+		}
+		if _, err := reader.underlying.Peek(1); err != nil {
+			return 0, err
+		}
+		// the next segment can NOT be empty
+		current, err := reader.open(reader.underlying)
+		if err != nil {
+			return 0, truncatedError(err)
+		}
+		reader.current = current
+	}
+}
+
+func compressionOf(pathname string) Compression {
+	for _, compression := range []Compression{
+		CompressGzip, CompressZlib, CompressFlate} {
+		if strings.HasSuffix(pathname, compression.Ext()) {
+			return compression
+		}
+	}
+	return CompressNone
+}
+
+func isTruncated(err error) bool {
+	return err == io.EOF || err == io.ErrUnexpectedEOF
+}
+
+// naturalLess compares the strings, in which the numbers are compared
+// numerically, e.g. "app.999.log" is less than "app.1000.log".
+func naturalLess(left, right string) bool {
+	for left != "" && right != "" {
+		leftNum, rightNum := isDigit(left[0]), isDigit(right[0])
+		if leftNum && rightNum {
+			leftDigits, rightDigits := leadingDigits(left), leadingDigits(right)
+			trimmedLeft := strings.TrimLeft(leftDigits, "0")
+			trimmedRight := strings.TrimLeft(rightDigits, "0")
+			if len(trimmedLeft) != len(trimmedRight) {
+				return len(trimmedLeft) < len(trimmedRight)
+			}
+			if trimmedLeft != trimmedRight {
+				return trimmedLeft < trimmedRight
+			}
+			left, right = left[len(leftDigits):], right[len(rightDigits):]
+			continue
+		}
+		if left[0] != right[0] {
+			return left[0] < right[0]
+		}
+		left, right = left[1:], right[1:]
+	}
+	return len(left) < len(right)
+}
+
+func leadingDigits(str string) string {
+	i := 0
+	for i < len(str) && isDigit(str[i]) {
+		i++
+	}
+	return str[:i]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,172 @@
+package file
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type logFile struct {
+	Pathname string
+	// the digits of the date and time in the name, used to sort log files
+	Key        string
+	Size       int64
+	ModTime    time.Time
+	Compressed bool
+	// whether it is of a previous process of the program with the default Base
+	Previous bool
+}
+
+func (writer *Writer) needRetention() bool {
+	return writer.config.MaxFiles > 0 ||
+		writer.config.MaxAge > 0 ||
+		writer.config.MaxTotalSize > 0
+}
+
+// applyRetention removes the log files beyond the limits from the oldest one
+// and then removes the empty day directories. It returns the first error that
+// occurs.
+func (writer *Writer) applyRetention() error {
+	files, dirs, err := writer.listFiles()
+	if err != nil {
+		return err
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Key > files[j].Key
+	})
+
+	var firstErr error
+	now := time.Now()
+	count := 0
+	var totalSize int64
+	for _, file := range files {
+		count++
+		totalSize += file.Size
+		if file.Pathname == writer.pathname ||
+			writer.compressor.Pending(file.Pathname) ||
+			!writer.expired(file, now, count, totalSize) {
+			continue
+		}
+		if err := os.Remove(file.Pathname); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		count--
+		totalSize -= file.Size
+	}
+
+	current := filepath.Dir(writer.pathname)
+	for _, dir := range dirs {
+		if dir == current {
+			continue
+		}
+		if isEmptyDir(dir) {
+			if err := os.Remove(dir); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+func (writer *Writer) expired(file *logFile, now time.Time, count int,
+	totalSize int64) bool {
+
+	config := &writer.config
+	return (config.MaxFiles > 0 && count > config.MaxFiles) ||
+		(config.MaxAge > 0 && now.Sub(file.ModTime) > config.MaxAge) ||
+		(config.MaxTotalSize > 0 && totalSize > config.MaxTotalSize)
+}
+
+// listFiles returns all the log files and day directories matching the
+// current naming scheme.
+func (writer *Writer) listFiles() (files []*logFile, dirs []string, err error) {
+	if writer.config.NoDirForDays {
+		files, err = writer.matchFiles(writer.config.Path, "")
+		return files, nil, err
+	}
+
+	dateRegexp := regexp.MustCompile("^" + datePattern(writer.config.DateStyle) + "$")
+	infos, err := ioutil.ReadDir(writer.config.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		matches := dateRegexp.FindStringSubmatch(info.Name())
+		if matches == nil {
+			continue
+		}
+		dir := filepath.Join(writer.config.Path, info.Name())
+		dayFiles, err := writer.matchFiles(dir, strings.Join(matches[1:], ""))
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, dayFiles...)
+		dirs = append(dirs, dir)
+	}
+	return files, dirs, nil
+}
+
+// matchFiles returns the log files in the dir matching the current naming
+// scheme, except those of the other processes still running. The datePrefix
+// is the prefix of the keys of the files.
+func (writer *Writer) matchFiles(dir, datePrefix string) ([]*logFile, error) {
+	fileRegexp := regexp.MustCompile(writer.filenamePattern())
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	pidded := writer.config.Base == defaultBase()
+	running := make(map[string]bool)
+	var files []*logFile
+	for _, info := range infos {
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		matches := fileRegexp.FindStringSubmatch(info.Name())
+		if matches == nil {
+			continue
+		}
+		last := len(matches) - 1
+		submatches := matches[1:last]
+		previous := false
+		if pidded {
+			if otherProcess(submatches[0], running) {
+				continue
+			}
+			previous = submatches[0] != strconv.Itoa(os.Getpid())
+			submatches = submatches[1:]
+		}
+		files = append(files, &logFile{
+			Pathname:   filepath.Join(dir, info.Name()),
+			Key:        datePrefix + writer.fileKey(submatches),
+			Size:       info.Size(),
+			ModTime:    info.ModTime(),
+			Compressed: matches[last] != "",
+			Previous:   previous,
+		})
+	}
+	return files, nil
+}
+
+func isEmptyDir(dir string) bool {
+	file, err := os.Open(dir)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	_, err = file.Readdirnames(1)
+	return err == io.EOF
+}
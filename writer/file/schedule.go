@@ -0,0 +1,84 @@
+package file
+
+import (
+	"time"
+)
+
+// period returns the rotation period [start, end) that the tm is in.
+// A day always starts a new period, and it is split further by RotateEvery
+// and RotateAt. The boundaries are the wall clock times in the Location, even
+// on the days of DST changes.
+func (writer *Writer) period(tm time.Time) (start, end time.Time) {
+	start, end = at(tm, 0), at(tm, time.Hour*24)
+	if every := writer.config.RotateEvery; every > 0 {
+		n := clockOf(tm) / every
+		start = at(tm, n*every)
+		if next := at(tm, (n+1)*every); next.Before(end) {
+			end = next
+		}
+	}
+	for _, offset := range writer.config.RotateAt {
+		boundary := at(tm, offset)
+		if !boundary.After(tm) {
+			if boundary.After(start) {
+				start = boundary
+			}
+		} else if boundary.Before(end) {
+			end = boundary
+		}
+	}
+	return start, end
+}
+
+// at returns the time when the wall clock reads the clock, an offset from the
+// midnight, on the day of the tm in the Location of the tm. If the clock is
+// skipped by a DST change, it returns the time of the change. If the clock
+// is repeated, it returns the first one.
+func at(tm time.Time, clock time.Duration) time.Time {
+	year, month, day := tm.Date()
+	wall := time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Add(clock)
+	date := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(),
+		wall.Minute(), wall.Second(), wall.Nanosecond(), tm.Location())
+	if !civil(date).Before(wall) {
+		return date
+	}
+	// the time.Date normalizes the clock skipped to a time before the change,
+	// and the change is searched between it and the same clock after it
+	low, high := date, date.Add(wall.Sub(civil(date)))
+	for high.Sub(low) > time.Second {
+		mid := low.Add(high.Sub(low) / 2)
+		if civil(mid).Before(wall) {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return high.Truncate(time.Second)
+}
+
+// clockOf returns the wall clock of the tm as an offset from the midnight.
+func clockOf(tm time.Time) time.Duration {
+	return time.Duration(tm.Hour())*time.Hour +
+		time.Duration(tm.Minute())*time.Minute +
+		time.Duration(tm.Second())*time.Second +
+		time.Duration(tm.Nanosecond())
+}
+
+// civil returns the wall clock date and time of the tm in UTC, so that they
+// can be compared regardless of the Location.
+func civil(tm time.Time) time.Time {
+	return time.Date(tm.Year(), tm.Month(), tm.Day(), tm.Hour(), tm.Minute(),
+		tm.Second(), tm.Nanosecond(), time.UTC)
+}
+
+func equalDurations(left, right []time.Duration) bool {
+	if len(left) != len(right) {
+		return false
+	}
+	for i := range left {
+		if left[i] != right[i] {
+			return false
+		}
+	}
+	return true
+}
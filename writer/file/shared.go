@@ -0,0 +1,27 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const sharedLockExt = ".lock"
+
+// lockShared opens the lock file of the log files shared by processes and
+// locks it exclusively. The lock is released when the returned file is closed.
+func (writer *Writer) lockShared() (*os.File, error) {
+	if err := os.MkdirAll(writer.config.Path, writer.config.DirPerm); err != nil {
+		return nil, err
+	}
+	pathname := filepath.Join(writer.config.Path,
+		"."+writer.config.Base+sharedLockExt)
+	file, err := os.OpenFile(pathname, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
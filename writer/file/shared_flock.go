@@ -0,0 +1,21 @@
+//go:build linux || darwin || freebsd || dragonfly || netbsd || openbsd || illumos
+
+package file
+
+import (
+	"os"
+	"syscall"
+)
+
+// whether Config.Shared is supported on the platform
+const sharedSupported = true
+
+// lockFile locks the file exclusively with flock.
+func lockFile(file *os.File) error {
+	for {
+		err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin && !freebsd && !dragonfly && !netbsd && !openbsd && !illumos
+
+package file
+
+import (
+	"errors"
+	"os"
+)
+
+// whether Config.Shared is supported on the platform
+const sharedSupported = false
+
+func lockFile(file *os.File) error {
+	return errors.New("flock is NOT supported on the platform")
+}
@@ -0,0 +1,91 @@
+package file
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+)
+
+var reopener struct {
+	// the Writers with ReopenOnSignal
+	writers map[*Writer]bool
+	signals chan os.Signal
+	lock    sync.Mutex
+}
+
+// HandleSignals starts to reopen the log files of all the Writers with
+// ReopenOnSignal when any of the signals is received. If no signal is
+// specified, syscall.SIGUSR1 and syscall.SIGHUP are handled on Unix, and
+// nothing is done on the other platforms, e.g. Windows. Calling it again
+// replaces the signals handled.
+//
+// A reopen is done with the lock of each Writer, so it is safe while logs are
+// being written. If it fails, the error is reported with the next log.
+// Signals are also delivered to other handlers registered by signal.Notify,
+// e.g. the Watcher of the package config.
+func HandleSignals(signals ...os.Signal) {
+	reopener.lock.Lock()
+	defer reopener.lock.Unlock()
+
+	if len(signals) == 0 {
+		signals = defaultSignals
+	}
+	// signal.Notify relays all signals without any signal specified
+	if len(signals) == 0 {
+		return
+	}
+	if reopener.signals == nil {
+		reopener.signals = make(chan os.Signal, 1)
+		go serveSignals(reopener.signals)
+	} else {
+		signal.Stop(reopener.signals)
+	}
+	signal.Notify(reopener.signals, signals...)
+}
+
+// StopSignals stops handling the signals by HandleSignals.
+func StopSignals() {
+	reopener.lock.Lock()
+	defer reopener.lock.Unlock()
+
+	if reopener.signals != nil {
+		signal.Stop(reopener.signals)
+		close(reopener.signals)
+		reopener.signals = nil
+	}
+}
+
+func serveSignals(signals <-chan os.Signal) {
+	for range signals {
+		reopener.lock.Lock()
+		writers := make([]*Writer, 0, len(reopener.writers))
+		for writer := range reopener.writers {
+			writers = append(writers, writer)
+		}
+		reopener.lock.Unlock()
+
+		for _, writer := range writers {
+			if err := writer.Reopen(); err != nil {
+				writer.lock.Lock()
+				writer.pendingErr = err
+				writer.lock.Unlock()
+			}
+		}
+	}
+}
+
+// register adds the Writer to or removes it from the Writers reopened on
+// signals.
+func register(writer *Writer, reopenOnSignal bool) {
+	reopener.lock.Lock()
+	defer reopener.lock.Unlock()
+
+	if reopenOnSignal {
+		if reopener.writers == nil {
+			reopener.writers = make(map[*Writer]bool)
+		}
+		reopener.writers[writer] = true
+	} else {
+		delete(reopener.writers, writer)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package file
+
+import "os"
+
+// no signal is handled by HandleSignals by default, e.g. on Windows
+var defaultSignals []os.Signal
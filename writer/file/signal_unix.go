@@ -0,0 +1,11 @@
+//go:build unix
+
+package file
+
+import (
+	"os"
+	"syscall"
+)
+
+// the signals handled by HandleSignals by default
+var defaultSignals = []os.Signal{syscall.SIGUSR1, syscall.SIGHUP}
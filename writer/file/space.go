@@ -0,0 +1,272 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/gxlog/gxlog/iface"
+)
+
+// The SpacePolicy defines what a Writer does when the disk space is low.
+type SpacePolicy int
+
+// All available space policies here.
+const (
+	// drops logs with a level lower than SpaceLevel
+	SpaceDrop SpacePolicy = iota
+	// removes the oldest log files until the disk space is NOT low
+	SpaceRetention
+	// drops all logs, i.e. pauses writing
+	SpacePause
+)
+
+// A LowSpaceError is reported by the ErrorHandler once when the disk space
+// becomes low.
+type LowSpaceError struct {
+	// the free space of the file system of the Path, -1 if NOT checked
+	Free int64
+	// the total size of the files in the Path, -1 if NOT checked
+	Usage  int64
+	Policy SpacePolicy
+}
+
+func (err *LowSpaceError) Error() string {
+	return fmt.Sprintf("writer/file: low disk space: free: %d, usage: %d, policy: %s",
+		err.Free, err.Usage, err.Policy)
+}
+
+// String returns the name of the policy.
+func (policy SpacePolicy) String() string {
+	switch policy {
+	case SpaceDrop:
+		return "drop"
+	case SpaceRetention:
+		return "retention"
+	case SpacePause:
+		return "pause"
+	}
+	return fmt.Sprintf("SpacePolicy(%d)", int(policy))
+}
+
+// A SpaceRecoveredError is reported by the ErrorHandler once when the disk
+// space is NOT low any more.
+type SpaceRecoveredError struct {
+	// the count of logs dropped or failed to write when the disk space was low
+	Dropped int64
+}
+
+func (err *SpaceRecoveredError) Error() string {
+	return fmt.Sprintf("writer/file: disk space recovered, %d logs dropped",
+		err.Dropped)
+}
+
+func (writer *Writer) needSpaceCheck() bool {
+	return writer.config.MinFreeSpace > 0 || writer.config.MaxDirUsage > 0
+}
+
+// checkSpace checks the disk space at the CheckInterval and reports the
+// changes of the state.
+func (writer *Writer) checkSpace(record *iface.Record) {
+	var lowErr *LowSpaceError
+	if writer.needSpaceCheck() {
+		if time.Since(writer.spaceTime) < writer.config.CheckInterval {
+			return
+		}
+		writer.spaceTime = time.Now()
+
+		var err error
+		lowErr, err = writer.lowSpace()
+		if err == nil && lowErr != nil &&
+			writer.config.SpacePolicy == SpaceRetention {
+			err = writer.removeOldest(lowErr)
+			if err == nil {
+				lowErr, err = writer.lowSpace()
+			}
+		}
+		if err != nil {
+			writer.reportError(record, err)
+			return
+		}
+	}
+
+	if lowErr != nil && !writer.spaceLow {
+		writer.spaceLow = true
+		writer.dropped = 0
+		writer.failReported = false
+		writer.reportError(record, lowErr)
+	} else if lowErr == nil && writer.spaceLow {
+		writer.spaceLow = false
+		writer.reportError(record, &SpaceRecoveredError{Dropped: writer.dropped})
+	}
+}
+
+// dropByLowSpace reports whether the log of the record is dropped because the
+// disk space is low.
+func (writer *Writer) dropByLowSpace(record *iface.Record) bool {
+	if !writer.spaceLow {
+		return false
+	}
+	switch writer.config.SpacePolicy {
+	case SpaceDrop:
+		if record.Level >= writer.config.SpaceLevel {
+			return false
+		}
+	case SpaceRetention:
+		return false
+	}
+	writer.dropped++
+	return true
+}
+
+// lowSpace returns a LowSpaceError if the disk space is low.
+func (writer *Writer) lowSpace() (*LowSpaceError, error) {
+	lowErr := &LowSpaceError{Free: -1, Usage: -1, Policy: writer.config.SpacePolicy}
+	low := false
+	if writer.config.MinFreeSpace > 0 {
+		free, err := freeSpace(writer.config.Path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		lowErr.Free = free
+		low = lowErr.Free < writer.config.MinFreeSpace
+	}
+	if writer.config.MaxDirUsage > 0 {
+		if writer.usageErr != nil {
+			err := writer.usageErr
+			writer.usageErr = nil
+			return nil, err
+		}
+		if writer.usage >= 0 {
+			lowErr.Usage = writer.usage
+			low = low || writer.usage > writer.config.MaxDirUsage
+		}
+	}
+	if !low {
+		return nil, nil
+	}
+	return lowErr, nil
+}
+
+// walkPath updates the total size of the files in the Path. The Path is
+// walked with the lock held only if it has NOT been walked yet. After that it
+// is walked at the CheckInterval in background, so that a large Path does NOT
+// block logs, which are checked with the usage of the last walk in the
+// meantime.
+func (writer *Writer) walkPath() {
+	if writer.config.MaxDirUsage == 0 || writer.walking {
+		return
+	}
+	if writer.usage < 0 {
+		writer.usage, writer.usageErr = dirUsage(writer.config.Path)
+		writer.walkTime = time.Now()
+		return
+	}
+	if time.Since(writer.walkTime) < writer.config.CheckInterval {
+		return
+	}
+	writer.walkTime = time.Now()
+	writer.walking = true
+	go writer.refreshUsage(writer.config.Path)
+}
+
+// refreshUsage walks the path without the lock and then updates the usage if
+// the Path is NOT changed in the meantime.
+func (writer *Writer) refreshUsage(path string) {
+	usage, err := dirUsage(path)
+
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.walking = false
+	if path == writer.config.Path && writer.usage >= 0 {
+		writer.usage, writer.usageErr = usage, err
+		// to check the disk space with the usage at the next log
+		writer.spaceTime = time.Time{}
+	}
+}
+
+// removeOldest removes the oldest log files except the current one until
+// the size removed makes up the shortage of the disk space.
+func (writer *Writer) removeOldest(lowErr *LowSpaceError) error {
+	var shortage int64
+	if lowErr.Free >= 0 && lowErr.Free < writer.config.MinFreeSpace {
+		shortage = writer.config.MinFreeSpace - lowErr.Free
+	}
+	if lowErr.Usage > writer.config.MaxDirUsage && writer.config.MaxDirUsage > 0 &&
+		lowErr.Usage-writer.config.MaxDirUsage > shortage {
+		shortage = lowErr.Usage - writer.config.MaxDirUsage
+	}
+
+	files, _, err := writer.listFiles()
+	if err != nil {
+		return err
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Key < files[j].Key
+	})
+	for _, file := range files {
+		if shortage <= 0 {
+			break
+		}
+		if file.Pathname == writer.pathname ||
+			writer.compressor.Pending(file.Pathname) {
+			continue
+		}
+		if err := os.Remove(file.Pathname); err != nil {
+			return err
+		}
+		shortage -= file.Size
+		if writer.usage >= 0 {
+			writer.usage -= file.Size
+		}
+	}
+	return nil
+}
+
+// reportWriteError reports the error of writing the log. When the disk space
+// is low, only the first error is reported and the logs failed to write after
+// it are counted as dropped, so that the ErrorHandler is NOT flooded when the
+// disk is full.
+func (writer *Writer) reportWriteError(bs []byte, record *iface.Record, err error) {
+	if writer.spaceLow {
+		if writer.failReported {
+			writer.dropped++
+			return
+		}
+		writer.failReported = true
+	}
+	if writer.config.ErrorHandler != nil {
+		writer.config.ErrorHandler(bs, record, err)
+	}
+}
+
+func (writer *Writer) reportError(record *iface.Record, err error) {
+	if writer.config.ErrorHandler != nil {
+		writer.config.ErrorHandler(nil, record, err)
+	}
+}
+
+// dirUsage returns the total size of the regular files in the dir and its
+// subdirectories.
+func dirUsage(dir string) (int64, error) {
+	var usage int64
+	err := filepath.Walk(dir, func(pathname string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.Mode().IsRegular() {
+			usage += info.Size()
+		}
+		return nil
+	})
+	return usage, err
+}
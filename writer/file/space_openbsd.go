@@ -0,0 +1,16 @@
+package file
+
+import "syscall"
+
+// whether Config.MinFreeSpace is supported on the platform
+const freeSpaceSupported = true
+
+// freeSpace returns the free space available to the process of the file
+// system of the path.
+func freeSpace(path string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return int64(stat.F_bavail) * int64(stat.F_bsize), nil
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !freebsd && !dragonfly && !openbsd && !windows
+
+package file
+
+import "errors"
+
+// whether Config.MinFreeSpace is supported on the platform
+const freeSpaceSupported = false
+
+func freeSpace(path string) (int64, error) {
+	return 0, errors.New("free space is NOT supported on the platform")
+}
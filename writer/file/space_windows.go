@@ -0,0 +1,30 @@
+//go:build windows
+
+package file
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// whether Config.MinFreeSpace is supported on the platform
+const freeSpaceSupported = true
+
+var procGetDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").
+	NewProc("GetDiskFreeSpaceExW")
+
+// freeSpace returns the free space available to the process of the volume
+// of the path.
+func freeSpace(path string) (int64, error) {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var free uint64
+	ret, _, err := procGetDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(&free)), 0, 0)
+	if ret == 0 {
+		return 0, err
+	}
+	return int64(free), nil
+}
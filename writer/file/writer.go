@@ -22,10 +22,42 @@ type Writer struct {
 	config Config
 
 	writer    io.WriteCloser
+	file      *os.File
 	pathname  string
 	checkTime time.Time
-	day       int
-	fileSize  int64
+	// the current log file is used for logs in [periodStart, periodEnd)
+	periodStart time.Time
+	periodEnd   time.Time
+	fileSize    int64
+
+	// nil if BufferSize is 0
+	buffer     *bufferedWriter
+	flushTimer *time.Timer
+	// the error occurred in background, e.g. when flushed by the timer
+	pendingErr error
+	unsynced   int
+	syncTime   time.Time
+	// armed when logs are written but NOT synced with Sync.Interval
+	syncTimer *time.Timer
+
+	spaceTime time.Time
+	// whether the disk space is low
+	spaceLow bool
+	// the count of logs dropped when the disk space is low
+	dropped int64
+	// whether an error of writing is reported since the disk space is low
+	failReported bool
+	// the total size of the files in the Path, -1 if NOT walked yet
+	usage    int64
+	usageErr error
+	// the last time to walk the Path in background
+	walkTime time.Time
+	// whether the Path is being walked in background
+	walking bool
+
+	compressor compressor
+	// whether the interrupted compressions have been recovered
+	recovered bool
 
 	lock sync.Mutex
 }
@@ -36,15 +68,35 @@ func Open(config Config) (*Writer, error) {
 	if err := config.check(); err != nil {
 		return nil, fmt.Errorf("writer/file.Open: %v", err)
 	}
-	return &Writer{config: config}, nil
+	writer := &Writer{config: config, usage: -1}
+	if config.ReopenOnSignal {
+		register(writer, true)
+	}
+	return writer, nil
 }
 
-// Close closes the Writer.
+// CheckConfig checks the config the same as Open does, but it does NOT create
+// a Writer.
+func CheckConfig(config Config) error {
+	config.setDefaults()
+	if err := config.check(); err != nil {
+		return fmt.Errorf("writer/file.CheckConfig: %v", err)
+	}
+	return nil
+}
+
+// Close closes the Writer. It waits until all the background compressions
+// are done.
 func (writer *Writer) Close() error {
 	writer.lock.Lock()
-	defer writer.lock.Unlock()
+	register(writer, false)
+	err := writer.closeFile()
+	writer.lock.Unlock()
 
-	if err := writer.closeFile(); err != nil {
+	// OnCompressed may call methods of the Writer, so the lock is released
+	// before waiting for the compressions
+	writer.compressor.Wait()
+	if err != nil {
 		return fmt.Errorf("writer/file.Close: %v", err)
 	}
 	return nil
@@ -55,14 +107,28 @@ func (writer *Writer) Write(bs []byte, record *iface.Record) {
 	writer.lock.Lock()
 	defer writer.lock.Unlock()
 
+	if writer.pendingErr != nil {
+		if writer.config.ErrorHandler != nil {
+			writer.config.ErrorHandler(nil, record, writer.pendingErr)
+		}
+		writer.pendingErr = nil
+	}
+	writer.walkPath()
+	writer.checkSpace(record)
+	if writer.dropByLowSpace(record) {
+		return
+	}
 	err := writer.checkFile(record)
 	if err == nil {
 		var n int
 		n, err = writer.writer.Write(bs)
 		writer.fileSize += int64(n)
 	}
-	if err != nil && writer.config.ErrorHandler != nil {
-		writer.config.ErrorHandler(bs, record, err)
+	if err == nil {
+		err = writer.afterWrite(record)
+	}
+	if err != nil {
+		writer.reportWriteError(bs, record, err)
 	}
 }
 
@@ -76,7 +142,8 @@ func (writer *Writer) Config() Config {
 
 // SetConfig sets the config to the Writer.
 // If the config is invalid, it returns an error and the Config of the Writer
-// is left to be unchanged.
+// is left to be unchanged. If the current log file fails to close, the error
+// is reported by the ErrorHandler with the next log.
 func (writer *Writer) SetConfig(config Config) error {
 	writer.lock.Lock()
 	defer writer.lock.Unlock()
@@ -105,33 +172,75 @@ func (writer *Writer) UpdateConfig(fn func(Config) Config) error {
 	return nil
 }
 
+// Reopen closes the current log file, and then the next log is written to
+// a new log file, or to the log file with the Filename reopened. It is used
+// to cooperate with external log rotation tools.
+func (writer *Writer) Reopen() error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if err := writer.closeFile(); err != nil {
+		return fmt.Errorf("writer/file.Reopen: %v", err)
+	}
+	return nil
+}
+
 func (writer *Writer) checkFile(record *iface.Record) error {
-	if writer.writer == nil ||
-		writer.day != record.Time.YearDay() ||
-		writer.fileSize >= writer.config.MaxFileSize {
+	if writer.config.Shared && writer.file != nil {
+		// other processes may have appended to it
+		writer.fileSize = sizeOf(writer.file)
+	}
+	if writer.writer == nil || writer.needRotation(record) {
 		return writer.createFile(record)
 	} else if time.Since(writer.checkTime) >= writer.config.CheckInterval {
 		writer.checkTime = time.Now()
-		if _, err := os.Stat(writer.pathname); err != nil {
+		if !writer.fileExists() {
 			return writer.createFile(record)
 		}
 	}
 	return nil
 }
 
+// needRotation reports whether the current log file is full or the log of
+// the record is NOT in the current rotation period. Log files with the
+// Filename are never rotated.
+func (writer *Writer) needRotation(record *iface.Record) bool {
+	if writer.config.Filename != "" {
+		return false
+	}
+	return record.Time.Before(writer.periodStart) ||
+		!record.Time.Before(writer.periodEnd) ||
+		writer.fileSize >= writer.config.MaxFileSize
+}
+
+// fileExists reports whether the current log file still exists with its
+// pathname, i.e. it is neither removed nor replaced, e.g. renamed by a log
+// rotation tool, after which a new file may be created with the pathname.
+func (writer *Writer) fileExists() bool {
+	info, err := os.Stat(writer.pathname)
+	if err != nil {
+		return false
+	}
+	current, err := writer.file.Stat()
+	return err == nil && os.SameFile(info, current)
+}
+
 func (writer *Writer) createFile(record *iface.Record) error {
 	if err := writer.closeFile(); err != nil {
 		return err
 	}
 
-	path := writer.formatPath(record.Time)
-	if err := os.MkdirAll(path, writer.config.DirPerm); err != nil {
-		return err
+	if writer.config.Shared {
+		// only one process creates the next log file and the others append to it
+		lock, err := writer.lockShared()
+		if err != nil {
+			return err
+		}
+		defer lock.Close()
 	}
 
-	filename := writer.formatFilename(record.Time)
-	pathname := filepath.Join(path, filename)
-	file, err := os.Create(pathname)
+	tm := record.Time.In(writer.config.Location)
+	file, pathname, fileSize, err := writer.openLogFile(tm)
 	if err != nil {
 		return err
 	}
@@ -144,6 +253,13 @@ func (writer *Writer) createFile(record *iface.Record) error {
 			wt.Close()
 			return err
 		}
+	} else if writer.config.PublicKey != "" {
+		// newHybridEncrypter will return the input writer when an error occurs
+		wt, err = newHybridEncrypter(wt, writer.config.PublicKey, writer.config.KeyID)
+		if err != nil {
+			wt.Close()
+			return err
+		}
 	}
 	if writer.config.GzipLevel != flate.NoCompression {
 		// newGzipWriter will return the input writer when an error occurs
@@ -153,21 +269,120 @@ func (writer *Writer) createFile(record *iface.Record) error {
 			return err
 		}
 	}
+	writer.buffer = nil
+	if writer.config.BufferSize > 0 {
+		writer.buffer = newBufferedWriter(wt, writer.config.BufferSize)
+		wt = writer.buffer
+	}
 
 	writer.writer = wt
+	writer.file = file
 	writer.pathname = pathname
-	writer.day = record.Time.YearDay()
-	writer.fileSize = 0
+	writer.checkTime = time.Now()
+	writer.periodStart, writer.periodEnd = writer.period(tm)
+	writer.fileSize = fileSize
+	writer.unsynced = 0
+	writer.syncTime = time.Now()
+	if err := writer.writePreamble(); err != nil {
+		return err
+	}
 
+	if writer.config.Symlink != "" {
+		if err := writer.updateSymlink(); err != nil &&
+			writer.config.ErrorHandler != nil {
+			writer.config.ErrorHandler(nil, record, err)
+		}
+	}
+	if writer.config.Compression != CompressNone && !writer.recovered {
+		writer.recovered = true
+		if err := writer.recoverCompression(); err != nil &&
+			writer.config.ErrorHandler != nil {
+			writer.config.ErrorHandler(nil, record, err)
+		}
+	}
+	if writer.needRetention() {
+		if err := writer.applyRetention(); err != nil &&
+			writer.config.ErrorHandler != nil {
+			writer.config.ErrorHandler(nil, record, err)
+		}
+	}
 	return nil
 }
 
+// openLogFile opens the log file for the logs at the tm and returns its size.
+func (writer *Writer) openLogFile(tm time.Time) (*os.File, string, int64, error) {
+	if writer.config.Filename != "" {
+		return writer.openFixedFile()
+	}
+	path := writer.formatPath(tm)
+	if err := os.MkdirAll(path, writer.config.DirPerm); err != nil {
+		return nil, "", 0, err
+	}
+	if writer.config.Append || writer.config.Shared {
+		file, pathname, size, err := writer.appendFile(path, tm)
+		if err != nil || file != nil {
+			return file, pathname, size, err
+		}
+	}
+	file, pathname, err := writer.openFile(path, tm)
+	return file, pathname, 0, err
+}
+
+// openFixedFile opens the log file with the Filename to append to it.
+func (writer *Writer) openFixedFile() (*os.File, string, int64, error) {
+	pathname := filepath.Join(writer.config.Path, writer.config.Filename)
+	if err := os.MkdirAll(filepath.Dir(pathname), writer.config.DirPerm); err != nil {
+		return nil, "", 0, err
+	}
+	file, err := os.OpenFile(pathname, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	return file, pathname, sizeOf(file), nil
+}
+
+// openFile creates a new log file in the path. In the TimeSequence style,
+// the file is created exclusively with the next sequence number. With Shared,
+// the file is opened in the append mode.
+func (writer *Writer) openFile(path string, tm time.Time) (*os.File, string, error) {
+	flag := os.O_WRONLY | os.O_CREATE
+	if writer.config.Shared {
+		flag |= os.O_APPEND
+	}
+	if writer.config.TimeStyle != TimeSequence {
+		pathname := filepath.Join(path, writer.formatFilename(tm, 0))
+		if !writer.config.Shared {
+			flag |= os.O_TRUNC
+		}
+		file, err := os.OpenFile(pathname, flag, 0666)
+		return file, pathname, err
+	}
+
+	seq, err := writer.nextSequence(path, tm)
+	if err != nil {
+		return nil, "", err
+	}
+	for {
+		pathname := filepath.Join(path, writer.formatFilename(tm, seq))
+		file, err := os.OpenFile(pathname, flag|os.O_EXCL, 0666)
+		if !os.IsExist(err) {
+			return file, pathname, err
+		}
+		seq++
+	}
+}
+
 func (writer *Writer) closeFile() error {
 	if writer.writer != nil {
-		if err := writer.writer.Close(); err != nil {
+		// the log file can NOT be used any more even if an error occurs
+		err := writer.closeWriters()
+		writer.writer = nil
+		writer.file = nil
+		writer.buffer = nil
+		if err != nil {
 			return err
 		}
-		writer.writer = nil
+		writer.compressRotated()
 	}
 	return nil
 }
@@ -180,7 +395,9 @@ func (writer *Writer) formatPath(tm time.Time) string {
 	return path
 }
 
-func (writer *Writer) formatFilename(tm time.Time) string {
+// formatFilename returns the name of a log file. The seq is used only in
+// the TimeSequence style.
+func (writer *Writer) formatFilename(tm time.Time, seq int) string {
 	elements := []string{}
 	if writer.config.Base != "" {
 		elements = append(elements, writer.config.Base)
@@ -188,7 +405,11 @@ func (writer *Writer) formatFilename(tm time.Time) string {
 	if writer.config.NoDirForDays {
 		elements = append(elements, writer.formatDate(tm))
 	}
-	elements = append(elements, writer.formatTime(tm))
+	if writer.config.TimeStyle == TimeSequence {
+		elements = append(elements, fmt.Sprintf("%03d", seq))
+	} else {
+		elements = append(elements, writer.formatTime(tm))
+	}
 	return strings.Join(elements, writer.config.Separator) + writer.config.Ext
 }
 
@@ -222,6 +443,7 @@ func (writer *Writer) formatTime(tm time.Time) string {
 
 func (writer *Writer) needNewFile(config *Config) bool {
 	if config.Path != writer.config.Path ||
+		config.Filename != writer.config.Filename ||
 		config.Base != writer.config.Base ||
 		config.Ext != writer.config.Ext ||
 		config.Separator != writer.config.Separator ||
@@ -229,8 +451,17 @@ func (writer *Writer) needNewFile(config *Config) bool {
 		config.TimeStyle != writer.config.TimeStyle ||
 		config.GzipLevel != writer.config.GzipLevel ||
 		config.AESKey != writer.config.AESKey ||
+		config.PublicKey != writer.config.PublicKey ||
+		config.KeyID != writer.config.KeyID ||
+		config.BufferSize != writer.config.BufferSize ||
 		config.BlockMode != writer.config.BlockMode ||
-		config.NoDirForDays != writer.config.NoDirForDays {
+		config.NoDirForDays != writer.config.NoDirForDays ||
+		config.Symlink != writer.config.Symlink ||
+		config.Shared != writer.config.Shared ||
+		// a Location may be loaded again with the same name
+		config.Location.String() != writer.config.Location.String() ||
+		config.RotateEvery != writer.config.RotateEvery ||
+		!equalDurations(config.RotateAt, writer.config.RotateAt) {
 		return true
 	}
 	return false
@@ -242,9 +473,21 @@ func (writer *Writer) setConfig(config *Config) error {
 		return err
 	}
 	if writer.needNewFile(config) {
+		// the log file can NOT be used any more even if an error occurs, and
+		// the error is reported with the next log
 		if err := writer.closeFile(); err != nil {
-			return err
+			writer.pendingErr = err
 		}
+		writer.recovered = false
+	}
+	if config.Compression != writer.config.Compression {
+		writer.recovered = false
+	}
+	// to check the disk space with the config at the next log
+	writer.spaceTime = time.Time{}
+	writer.usage, writer.usageErr = -1, nil
+	if config.ReopenOnSignal != writer.config.ReopenOnSignal {
+		register(writer, config.ReopenOnSignal)
 	}
 	writer.config = *config
 	return nil
@@ -0,0 +1,74 @@
+//go:build linux || darwin || freebsd || dragonfly || netbsd || openbsd || illumos
+
+package file_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gxlog/gxlog/iface"
+	"github.com/gxlog/gxlog/writer/file"
+)
+
+func TestShared(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	const writers, logs, maxFileSize = 4, 500, 4096
+	config := file.Config{
+		Path:         dir,
+		Base:         "app",
+		NoDirForDays: true,
+		TimeStyle:    file.TimeSequence,
+		MaxFileSize:  maxFileSize,
+		Shared:       true,
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wt, err := file.Open(config)
+		if err != nil {
+			t.Fatalf("TestShared: %v", err)
+		}
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			defer wt.Close()
+			for j := 0; j < logs; j++ {
+				log := fmt.Sprintf("writer %d log %03d\n", id, j)
+				wt.Write([]byte(log), &iface.Record{Time: time.Now()})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	pathnames, _ := file.ListFiles(dir)
+	const logSize = len("writer 0 log 000\n")
+	if len(pathnames) < writers*logs*logSize/(maxFileSize+writers*logSize) {
+		t.Errorf("TestShared: too few files: %d", len(pathnames))
+	}
+	for _, pathname := range pathnames {
+		info, _ := os.Stat(pathname)
+		if info.Size() >= maxFileSize+writers*int64(logSize) {
+			t.Errorf("TestShared: too large: %s: %d", pathname, info.Size())
+		}
+	}
+	seen := make(map[string]bool)
+	for _, line := range strings.SplitAfter(readFiles(t, pathnames, file.ReaderConfig{}), "\n") {
+		if line == "" {
+			continue
+		}
+		var id, j int
+		if n, _ := fmt.Sscanf(line, "writer %d log %d\n", &id, &j); n != 2 ||
+			len(line) != logSize || seen[line] {
+			t.Fatalf("TestShared: bad or duplicate log: %q", line)
+		}
+		seen[line] = true
+	}
+	if len(seen) != writers*logs {
+		t.Errorf("TestShared: %d logs, want %d", len(seen), writers*logs)
+	}
+}
@@ -0,0 +1,1011 @@
+package file_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gxlog/gxlog/iface"
+	"github.com/gxlog/gxlog/writer/file"
+)
+
+func TestRetention(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	old := time.Now().Add(-time.Hour * 48)
+	createFiles(t, dir, old,
+		"app.20200101.000000.000000.log",
+		"app.20200102.000000.000000.log",
+		"app.20200103.000000.000000.log",
+		"other.20200101.000000.000000.log",
+		"app.20200101.000000.000000.log.bak")
+
+	wt, err := file.Open(file.Config{
+		Path:         dir,
+		Base:         "app",
+		NoDirForDays: true,
+		MaxFiles:     3,
+	})
+	if err != nil {
+		t.Fatalf("TestRetention: %v", err)
+	}
+	wt.Write([]byte("log\n"), &iface.Record{Time: time.Now()})
+	wt.Close()
+
+	names := listNames(t, dir)
+	current := "app." + time.Now().Format("20060102")
+	if len(names) != 5 ||
+		names[0] != "app.20200101.000000.000000.log.bak" ||
+		names[1] != "app.20200102.000000.000000.log" ||
+		names[2] != "app.20200103.000000.000000.log" ||
+		names[3][:len(current)] != current ||
+		names[4] != "other.20200101.000000.000000.log" {
+		t.Errorf("TestRetention: files: %v", names)
+	}
+}
+
+func TestRetentionOfPreviousProcesses(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	prog := filepath.Base(os.Args[0])
+	// a pid beyond the limits of pids, and the pid of init, which is running
+	dead, running := prog+".4194305.", prog+".1."
+	createFiles(t, dir, time.Now().Add(-time.Hour*48),
+		dead+"20200101.000000.000000.log",
+		dead+"20200102.000000.000000.log",
+		running+"20200101.000000.000000.log")
+
+	wt, err := file.Open(file.Config{
+		Path:         dir,
+		NoDirForDays: true,
+		MaxFiles:     2,
+	})
+	if err != nil {
+		t.Fatalf("TestRetentionOfPreviousProcesses: %v", err)
+	}
+	wt.Write([]byte("log\n"), &iface.Record{Time: time.Now()})
+	wt.Close()
+
+	names := listNames(t, dir)
+	current := prog + "." + strconv.Itoa(os.Getpid()) + "."
+	kept := map[string]bool{}
+	for _, name := range names {
+		if strings.HasPrefix(name, current) {
+			name = current
+		}
+		kept[name] = true
+	}
+	if len(names) != 3 || !kept[current] ||
+		!kept[running+"20200101.000000.000000.log"] ||
+		!kept[dead+"20200102.000000.000000.log"] {
+		t.Errorf("TestRetentionOfPreviousProcesses: files: %v", names)
+	}
+}
+
+func TestRetentionOfDayDirs(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	old := time.Now().Add(-time.Hour * 48)
+	createFiles(t, filepath.Join(dir, "2020-01-01"), old, "app.000000.000000.log")
+	createFiles(t, filepath.Join(dir, "2020-01-02"), old, "app.000000.000000.log",
+		"readme.txt")
+	createFiles(t, filepath.Join(dir, "archive"), old, "app.000000.000000.log")
+
+	wt, err := file.Open(file.Config{
+		Path:      dir,
+		Base:      "app",
+		DateStyle: file.DateDash,
+		MaxAge:    time.Hour * 24,
+	})
+	if err != nil {
+		t.Fatalf("TestRetentionOfDayDirs: %v", err)
+	}
+	wt.Write([]byte("log\n"), &iface.Record{Time: time.Now()})
+	wt.Close()
+
+	names := listNames(t, dir)
+	expect := []string{"2020-01-02", time.Now().Format("2006-01-02"), "archive"}
+	if !equalStrings(names, expect) {
+		t.Errorf("TestRetentionOfDayDirs: dirs: %v", names)
+	}
+	names = listNames(t, filepath.Join(dir, "2020-01-02"))
+	if !equalStrings(names, []string{"readme.txt"}) {
+		t.Errorf("TestRetentionOfDayDirs: files: %v", names)
+	}
+}
+
+func TestSymlinkAndSequence(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("TestSymlinkAndSequence: symbolic links need privileges")
+	}
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	day := filepath.Join(dir, now.Format("20060102"))
+	createFiles(t, day, now, "app.002.log")
+
+	wt, err := file.Open(file.Config{
+		Path:        dir,
+		Base:        "app",
+		TimeStyle:   file.TimeSequence,
+		MaxFileSize: 4,
+		Symlink:     "app.log",
+	})
+	if err != nil {
+		t.Fatalf("TestSymlinkAndSequence: %v", err)
+	}
+	for _, msg := range []string{"3rd\n", "4th\n"} {
+		wt.Write([]byte(msg), &iface.Record{Time: now})
+	}
+	wt.Close()
+
+	names := listNames(t, day)
+	if !equalStrings(names, []string{"app.002.log", "app.003.log", "app.004.log"}) {
+		t.Errorf("TestSymlinkAndSequence: files: %v", names)
+	}
+	bs, err := ioutil.ReadFile(filepath.Join(dir, "app.log"))
+	if err != nil || string(bs) != "4th\n" {
+		t.Errorf("TestSymlinkAndSequence: symlink: %q, %v", bs, err)
+	}
+	names = listNames(t, dir)
+	if !equalStrings(names, []string{filepath.Base(day), "app.log"}) {
+		t.Errorf("TestSymlinkAndSequence: files: %v", names)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	createFiles(t, dir, now,
+		"app.20200101.000000.000000.log",
+		"app.20200101.000000.000000.log.gz.tmp",
+		"app.20200102.000000.000000.log",
+		"app.20200102.000000.000000.log.gz")
+
+	var lock sync.Mutex
+	var compressed []string
+	wt, err := file.Open(file.Config{
+		Path:         dir,
+		Base:         "app",
+		NoDirForDays: true,
+		MaxFileSize:  4,
+		Compression:  file.CompressGzip,
+		OnCompressed: func(pathname string, err error) {
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				t.Errorf("TestCompression: %v", err)
+			}
+			compressed = append(compressed, filepath.Base(pathname))
+		},
+	})
+	if err != nil {
+		t.Fatalf("TestCompression: %v", err)
+	}
+	wt.Write([]byte("1st\n"), &iface.Record{Time: now})
+	wt.Write([]byte("2nd\n"), &iface.Record{Time: now.Add(time.Second)})
+	wt.Close()
+
+	names := listNames(t, dir)
+	if len(names) != 4 || len(compressed) != 3 {
+		t.Fatalf("TestCompression: files: %v, compressed: %v", names, compressed)
+	}
+	if names[0] != "app.20200101.000000.000000.log.gz" ||
+		names[1] != "app.20200102.000000.000000.log.gz" {
+		t.Errorf("TestCompression: files: %v", names)
+	}
+	// the compressed file of the 2nd name is created by createFiles
+	for i, expect := range map[int]string{0: "log\n", 2: "1st\n", 3: "2nd\n"} {
+		content := gunzipFile(t, filepath.Join(dir, names[i]))
+		if content != expect {
+			t.Errorf("TestCompression: content of %s: %q", names[i], content)
+		}
+	}
+}
+
+func TestCloseWithOnCompressed(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	var wt *file.Writer
+	wt, err := file.Open(file.Config{
+		Path:         dir,
+		Base:         "app",
+		NoDirForDays: true,
+		Compression:  file.CompressGzip,
+		OnCompressed: func(pathname string, err error) {
+			// a method of the Writer called within OnCompressed
+			wt.Config()
+		},
+	})
+	if err != nil {
+		t.Fatalf("TestCloseWithOnCompressed: %v", err)
+	}
+	wt.Write([]byte("log\n"), &iface.Record{Time: time.Now()})
+	done := make(chan struct{})
+	go func() {
+		wt.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatalf("TestCloseWithOnCompressed: Close deadlocks")
+	}
+}
+
+func TestRecoverCompression(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	prog := filepath.Base(os.Args[0])
+	// a pid beyond the limits of pids, and the pid of init, which is running
+	dead, running := prog+".4194305.", prog+".1."
+	createFiles(t, dir, time.Now(),
+		dead+"20200101.000000.000000.log",
+		dead+"20200102.000000.000000.log.zlib.tmp",
+		running+"20200101.000000.000000.log",
+		running+"20200101.000000.000000.log.gz.tmp")
+
+	wt, err := file.Open(file.Config{
+		Path:         dir,
+		NoDirForDays: true,
+		Compression:  file.CompressGzip,
+	})
+	if err != nil {
+		t.Fatalf("TestRecoverCompression: %v", err)
+	}
+	wt.Write([]byte("log\n"), &iface.Record{Time: time.Now()})
+	wt.Close()
+
+	names := listNames(t, dir)
+	current := prog + "." + strconv.Itoa(os.Getpid()) + "."
+	kept := map[string]bool{}
+	for _, name := range names {
+		if strings.HasPrefix(name, current) && strings.HasSuffix(name, ".log.gz") {
+			name = current
+		}
+		kept[name] = true
+	}
+	if len(names) != 4 || !kept[current] ||
+		!kept[dead+"20200101.000000.000000.log.gz"] ||
+		!kept[running+"20200101.000000.000000.log"] ||
+		!kept[running+"20200101.000000.000000.log.gz.tmp"] {
+		t.Errorf("TestRecoverCompression: files: %v", names)
+	}
+}
+
+func gunzipFile(t *testing.T, pathname string) string {
+	file, err := os.Open(pathname)
+	if err != nil {
+		t.Fatalf("%s: %v", t.Name(), err)
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("%s: %v", t.Name(), err)
+	}
+	bs, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("%s: %v", t.Name(), err)
+	}
+	return string(bs)
+}
+
+func TestSchedule(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	wt, err := file.Open(file.Config{
+		Path:         dir,
+		Base:         "app",
+		NoDirForDays: true,
+		Location:     time.FixedZone("UTC+8", 8*3600),
+		RotateEvery:  time.Hour,
+		RotateAt:     []time.Duration{time.Minute * 30},
+	})
+	if err != nil {
+		t.Fatalf("TestSchedule: %v", err)
+	}
+	for _, tm := range []string{
+		"2025-12-31T14:10:00Z",
+		"2025-12-31T14:50:00Z",
+		"2025-12-31T15:05:00Z",
+		"2025-12-31T16:10:00Z",
+		"2025-12-31T16:40:00Z",
+		"2026-12-31T16:10:00Z",
+	} {
+		record := &iface.Record{}
+		record.Time, _ = time.Parse(time.RFC3339, tm)
+		wt.Write([]byte(tm+"\n"), record)
+	}
+	wt.Close()
+
+	names := listNames(t, dir)
+	expect := []string{
+		"app.20251231.221000.000000.log",
+		"app.20251231.230500.000000.log",
+		"app.20260101.001000.000000.log",
+		"app.20260101.004000.000000.log",
+		"app.20270101.001000.000000.log",
+	}
+	if !equalStrings(names, expect) {
+		t.Errorf("TestSchedule: files: %v", names)
+	}
+
+	// the boundaries are the wall clock times on the day that DST starts
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("TestSchedule: %v", err)
+	}
+	dstDir := tempDir(t)
+	defer os.RemoveAll(dstDir)
+	wt, err = file.Open(file.Config{
+		Path:         dstDir,
+		Base:         "app",
+		NoDirForDays: true,
+		Location:     loc,
+		RotateEvery:  time.Hour * 6,
+		// skipped from 02:00 to 03:00
+		RotateAt: []time.Duration{time.Hour*2 + time.Minute*30},
+	})
+	if err != nil {
+		t.Fatalf("TestSchedule: %v", err)
+	}
+	for _, tm := range []string{
+		"2026-03-08T06:50:00Z", // 01:50 EST
+		"2026-03-08T07:10:00Z", // 03:10 EDT
+		"2026-03-08T09:30:00Z", // 05:30 EDT
+		"2026-03-08T10:30:00Z", // 06:30 EDT
+	} {
+		record := &iface.Record{}
+		record.Time, _ = time.Parse(time.RFC3339, tm)
+		wt.Write([]byte(tm+"\n"), record)
+	}
+	wt.Close()
+
+	names = listNames(t, dstDir)
+	expect = []string{
+		"app.20260308.015000.000000.log",
+		"app.20260308.031000.000000.log",
+		"app.20260308.063000.000000.log",
+	}
+	if !equalStrings(names, expect) {
+		t.Errorf("TestSchedule: DST: files: %v", names)
+	}
+}
+
+func TestBuffer(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	wt, err := file.Open(file.Config{
+		Path:          dir,
+		Base:          "app",
+		NoDirForDays:  true,
+		BufferSize:    1024,
+		FlushInterval: time.Millisecond * 50,
+		Sync:          file.SyncPolicy{Level: iface.Error},
+	})
+	if err != nil {
+		t.Fatalf("TestBuffer: %v", err)
+	}
+	defer wt.Close()
+
+	wt.Write([]byte("info\n"), &iface.Record{Time: time.Now(), Level: iface.Info})
+	pathnames, _ := file.ListFiles(dir)
+	if len(pathnames) != 1 {
+		t.Fatalf("TestBuffer: files: %v", pathnames)
+	}
+	if bs, _ := ioutil.ReadFile(pathnames[0]); len(bs) != 0 {
+		t.Errorf("TestBuffer: the log is NOT buffered: %q", bs)
+	}
+	for i := 0; ; i++ {
+		if bs, _ := ioutil.ReadFile(pathnames[0]); string(bs) == "info\n" {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("TestBuffer: the buffer is NOT flushed by the timer")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	wt.Write([]byte("error\n"), &iface.Record{Time: time.Now(), Level: iface.Error})
+	if bs, _ := ioutil.ReadFile(pathnames[0]); string(bs) != "info\nerror\n" {
+		t.Errorf("TestBuffer: the log is NOT synced: %q", bs)
+	}
+}
+
+func TestSetConfig(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	loadLocation := func() *time.Location {
+		loc, err := time.LoadLocation("America/New_York")
+		if err != nil {
+			t.Skipf("TestSetConfig: %v", err)
+		}
+		return loc
+	}
+	config := file.Config{
+		Path:         dir,
+		Base:         "app",
+		NoDirForDays: true,
+		Location:     loadLocation(),
+	}
+	wt, err := file.Open(config)
+	if err != nil {
+		t.Fatalf("TestSetConfig: %v", err)
+	}
+	defer wt.Close()
+	wt.Write([]byte("log 0\n"), &iface.Record{Time: time.Now()})
+
+	// the same Location loaded again does NOT create a new log file
+	config.Location = loadLocation()
+	config.MaxFiles = 10
+	if err := wt.SetConfig(config); err != nil {
+		t.Fatalf("TestSetConfig: %v", err)
+	}
+	wt.Write([]byte("log 1\n"), &iface.Record{Time: time.Now()})
+
+	if names := listNames(t, dir); len(names) != 1 {
+		t.Errorf("TestSetConfig: files: %v", names)
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	config := file.Config{Path: dir, Base: "app", ReopenOnSignal: true}
+	if err := file.CheckConfig(config); err != nil {
+		t.Errorf("TestCheckConfig: %v", err)
+	}
+	if names := listNames(t, dir); len(names) != 0 {
+		t.Errorf("TestCheckConfig: files: %v", names)
+	}
+	config.MaxFiles = -1
+	if err := file.CheckConfig(config); err == nil {
+		t.Errorf("TestCheckConfig: negative MaxFiles is NOT rejected")
+	}
+}
+
+func TestReader(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	const key = "70856575b161fbcca8fc12e1f70fc1c8"
+	wt, err := file.Open(file.Config{
+		Path:        dir,
+		Base:        "app",
+		TimeStyle:   file.TimeSequence,
+		MaxFileSize: 64,
+		GzipLevel:   flate.BestSpeed,
+		AESKey:      key,
+		BlockMode:   file.CTR,
+	})
+	if err != nil {
+		t.Fatalf("TestReader: %v", err)
+	}
+	var expect string
+	for i := 0; i < 100; i++ {
+		log := fmt.Sprintf("log %d\n", i)
+		wt.Write([]byte(log), &iface.Record{Time: time.Now()})
+		expect += log
+	}
+	wt.Close()
+
+	pathnames, err := file.ListFiles(dir)
+	if err != nil || len(pathnames) < 2 {
+		t.Fatalf("TestReader: files: %v, %v", pathnames, err)
+	}
+	config := file.ReaderConfig{AESKey: key, BlockMode: file.CTR}
+	if content := readFiles(t, pathnames, config); content != expect {
+		t.Errorf("TestReader: content: %q", content)
+	}
+
+	// truncate the last log file as if it is written by a crash
+	last := pathnames[len(pathnames)-1]
+	info, _ := os.Stat(last)
+	os.Truncate(last, info.Size()-16)
+	content := readFiles(t, pathnames, config)
+	if len(content) >= len(expect) || content != expect[:len(content)] {
+		t.Errorf("TestReader: truncated content: %q", content)
+	}
+}
+
+func TestListFiles(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	// in time order, with the pids of processes in the names
+	expect := []string{
+		"20260101/app.10.110000.000000.log",
+		"20260101/app.9.120000.000000.log",
+		"app.100.20260102.070000.000000.log",
+		"app.99.20260102.080000.000000.log.gz",
+		"20260103/app.40.001.log",
+		"20260103/app.5.002.log",
+		"app.log",
+	}
+	for i := len(expect) - 1; i >= 0; i-- {
+		createFiles(t, filepath.Join(dir, filepath.Dir(expect[i])), time.Now(),
+			filepath.Base(expect[i]))
+	}
+	createFiles(t, dir, time.Now(), ".app.lock", "app.1.20260102.090000.000000.log.gz.tmp")
+
+	pathnames, err := file.ListFiles(dir)
+	if err != nil {
+		t.Fatalf("TestListFiles: %v", err)
+	}
+	var names []string
+	for _, pathname := range pathnames {
+		name, _ := filepath.Rel(dir, pathname)
+		names = append(names, filepath.ToSlash(name))
+	}
+	if !equalStrings(names, expect) {
+		t.Errorf("TestListFiles: files: %v", names)
+	}
+}
+
+func TestGCM(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	const key = "70856575b161fbcca8fc12e1f70fc1c8"
+	wt, err := file.Open(file.Config{
+		Path:         dir,
+		Base:         "app",
+		NoDirForDays: true,
+		GzipLevel:    flate.BestSpeed,
+		AESKey:       key,
+		BlockMode:    file.GCM,
+	})
+	if err != nil {
+		t.Fatalf("TestGCM: %v", err)
+	}
+	var expect string
+	for i := 0; i < 100; i++ {
+		log := fmt.Sprintf("log %d\n", i)
+		wt.Write([]byte(log), &iface.Record{Time: time.Now()})
+		expect += log
+	}
+	wt.Close()
+
+	pathnames, _ := file.ListFiles(dir)
+	if len(pathnames) != 1 {
+		t.Fatalf("TestGCM: files: %v", pathnames)
+	}
+	data, _ := ioutil.ReadFile(pathnames[0])
+	config := file.ReaderConfig{AESKey: key, BlockMode: file.GCM}
+
+	content, err := readGCM(t, data, config)
+	if err != nil || content != expect {
+		t.Errorf("TestGCM: content: %q, %v", content, err)
+	}
+	content, err = readGCM(t, data[:len(data)-100], config)
+	if err != file.ErrTruncated || content != expect[:len(content)] {
+		t.Errorf("TestGCM: truncated: %q, %v", content, err)
+	}
+	data[len(data)/2] ^= 0x01
+	if _, err = readGCM(t, data, config); err != file.ErrCorrupted {
+		t.Errorf("TestGCM: tampered: %v", err)
+	}
+}
+
+func TestPublicKey(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("TestPublicKey: %v", err)
+	}
+	x25519Key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("TestPublicKey: %v", err)
+	}
+	keys := []struct {
+		ID      string
+		Public  crypto.PublicKey
+		Private crypto.PrivateKey
+	}{
+		{"rsa-1", &rsaKey.PublicKey, rsaKey},
+		{"x25519-1", x25519Key.PublicKey(), x25519Key},
+	}
+
+	privateKeys := make(map[string]string)
+	for _, key := range keys {
+		wt, err := file.Open(file.Config{
+			Path:         dir,
+			Base:         key.ID,
+			NoDirForDays: true,
+			GzipLevel:    flate.BestSpeed,
+			PublicKey:    encodePEM(t, "PUBLIC KEY", key.Public),
+			KeyID:        key.ID,
+		})
+		if err != nil {
+			t.Fatalf("TestPublicKey: %v", err)
+		}
+		wt.Write([]byte(key.ID+"\n"), &iface.Record{Time: time.Now()})
+		wt.Close()
+		privateKeys[key.ID] = encodePEM(t, "PRIVATE KEY", key.Private)
+	}
+
+	pathnames, _ := file.ListFiles(dir)
+	content := readFiles(t, pathnames, file.ReaderConfig{PrivateKeys: privateKeys})
+	if content != "rsa-1\nx25519-1\n" {
+		t.Errorf("TestPublicKey: content: %q", content)
+	}
+
+	delete(privateKeys, "rsa-1")
+	_, err = file.OpenReader(pathnames[0], file.ReaderConfig{PrivateKeys: privateKeys})
+	if err == nil {
+		t.Errorf("TestPublicKey: expect an error without the private key")
+	}
+}
+
+func TestAppend(t *testing.T) {
+	const key = "70856575b161fbcca8fc12e1f70fc1c8"
+	configs := []file.Config{
+		{},
+		{GzipLevel: flate.BestSpeed},
+		{GzipLevel: flate.BestSpeed, AESKey: key, BlockMode: file.GCM},
+	}
+	for _, config := range configs {
+		dir := tempDir(t)
+		config.Path = dir
+		config.Base = "app"
+		config.NoDirForDays = true
+		config.Append = true
+		readerConfig := file.ReaderConfig{AESKey: config.AESKey, BlockMode: config.BlockMode}
+
+		var expect string
+		for i := 0; i < 3; i++ {
+			wt, err := file.Open(config)
+			if err != nil {
+				t.Fatalf("TestAppend: %v", err)
+			}
+			log := fmt.Sprintf("log %d\n", i)
+			wt.Write([]byte(log), &iface.Record{Time: time.Now()})
+			wt.Close()
+			expect += log
+		}
+		pathnames, _ := file.ListFiles(dir)
+		if len(pathnames) != 1 {
+			t.Fatalf("TestAppend: files: %v", pathnames)
+		}
+		if content := readFiles(t, pathnames, readerConfig); content != expect {
+			t.Errorf("TestAppend: content: %q", content)
+		}
+
+		if config.GzipLevel != flate.NoCompression {
+			// NOT to append to a log file left incomplete by a crash
+			info, _ := os.Stat(pathnames[0])
+			os.Truncate(pathnames[0], info.Size()-4)
+			wt, _ := file.Open(config)
+			wt.Write([]byte("log\n"), &iface.Record{Time: time.Now()})
+			wt.Close()
+			if pathnames, _ = file.ListFiles(dir); len(pathnames) != 2 {
+				t.Errorf("TestAppend: incomplete: %v", pathnames)
+			}
+		}
+		os.RemoveAll(dir)
+	}
+
+	config := file.Config{Append: true, AESKey: key, BlockMode: file.CTR}
+	if _, err := file.Open(config); err == nil {
+		t.Errorf("TestAppend: Append with CTR is NOT rejected")
+	}
+}
+
+func TestReopen(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	wt, err := file.Open(file.Config{
+		Path:          dir,
+		Filename:      "app.log",
+		CheckInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("TestReopen: %v", err)
+	}
+	defer wt.Close()
+	pathname := filepath.Join(dir, "app.log")
+	write := func(log string) {
+		wt.Write([]byte(log), &iface.Record{Time: time.Now()})
+	}
+
+	write("log 0\n")
+	os.Rename(pathname, pathname+".1")
+	write("log 1\n")
+	if err := wt.Reopen(); err != nil {
+		t.Fatalf("TestReopen: %v", err)
+	}
+	write("log 2\n")
+
+	if bs, _ := ioutil.ReadFile(pathname + ".1"); string(bs) != "log 0\nlog 1\n" {
+		t.Errorf("TestReopen: renamed: %q", bs)
+	}
+	if bs, _ := ioutil.ReadFile(pathname); string(bs) != "log 2\n" {
+		t.Errorf("TestReopen: reopened: %q", bs)
+	}
+}
+
+func TestPreamble(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	const key = "70856575b161fbcca8fc12e1f70fc1c8"
+	wt, err := file.Open(file.Config{
+		Path:         dir,
+		Base:         "app",
+		NoDirForDays: true,
+		TimeStyle:    file.TimeSequence,
+		MaxFileSize:  1024,
+		GzipLevel:    flate.BestSpeed,
+		AESKey:       key,
+		BlockMode:    file.GCM,
+		Preamble: func(info *file.PreambleInfo) []byte {
+			preamble := file.DefaultPreamble(info)
+			return append(preamble, "# header: {{msg}}\n"...)
+		},
+	})
+	if err != nil {
+		t.Fatalf("TestPreamble: %v", err)
+	}
+	for i := 0; i < 300; i++ {
+		wt.Write([]byte(fmt.Sprintf("log %d\n", i)), &iface.Record{Time: time.Now()})
+	}
+	wt.Close()
+
+	pathnames, _ := file.ListFiles(dir)
+	if len(pathnames) < 2 {
+		t.Fatalf("TestPreamble: files: %v", pathnames)
+	}
+	config := file.ReaderConfig{AESKey: key, BlockMode: file.GCM}
+	for _, pathname := range pathnames {
+		content := readFiles(t, []string{pathname}, config)
+		expects := []string{
+			"# log file: " + pathname + "\n",
+			fmt.Sprintf("# pid: %d\n", os.Getpid()),
+			"# gzip level: 1\n",
+			"# encryption: aes-gcm\n",
+			"# header: {{msg}}\n",
+		}
+		if !strings.HasPrefix(content, expects[0]) {
+			t.Errorf("TestPreamble: %s: %q", pathname, content)
+		}
+		for _, expect := range expects[1:] {
+			if !strings.Contains(content, expect) {
+				t.Errorf("TestPreamble: %s: missing %q", pathname, expect)
+			}
+		}
+	}
+}
+
+func TestSpace(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	createFiles(t, dir, time.Now(), "other.1", "other.2", "other.3")
+	var errs []error
+	wt, err := file.Open(file.Config{
+		Path:          dir,
+		Base:          "app",
+		NoDirForDays:  true,
+		CheckInterval: time.Millisecond * 10,
+		MaxDirUsage:   10,
+		ErrorHandler: func(_ []byte, _ *iface.Record, err error) {
+			errs = append(errs, err)
+		},
+	})
+	if err != nil {
+		t.Fatalf("TestSpace: %v", err)
+	}
+	defer wt.Close()
+
+	for i := 0; i < 5; i++ {
+		wt.Write([]byte("info\n"), &iface.Record{Time: time.Now(), Level: iface.Info})
+	}
+	wt.Write([]byte("warn\n"), &iface.Record{Time: time.Now(), Level: iface.Warn})
+	os.Remove(filepath.Join(dir, "other.1"))
+	os.Remove(filepath.Join(dir, "other.2"))
+	time.Sleep(time.Millisecond * 20)
+	// the Path is walked in background and the log is checked with the usage
+	// of the last walk
+	wt.Write([]byte("info\n"), &iface.Record{Time: time.Now(), Level: iface.Info})
+	time.Sleep(time.Millisecond * 20)
+	wt.Write([]byte("info\n"), &iface.Record{Time: time.Now(), Level: iface.Info})
+
+	if len(errs) != 2 {
+		t.Fatalf("TestSpace: errors: %v", errs)
+	}
+	if lowErr, ok := errs[0].(*file.LowSpaceError); !ok || lowErr.Usage != 12 {
+		t.Errorf("TestSpace: low: %v", errs[0])
+	}
+	if recovered, ok := errs[1].(*file.SpaceRecoveredError); !ok ||
+		recovered.Dropped != 6 {
+		t.Errorf("TestSpace: recovered: %v", errs[1])
+	}
+	pathnames, _ := file.ListFiles(dir)
+	content := readFiles(t, pathnames[:1], file.ReaderConfig{})
+	if content != "warn\ninfo\n" {
+		t.Errorf("TestSpace: content: %q", content)
+	}
+}
+
+func TestSpaceFull(t *testing.T) {
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("TestSpaceFull: /dev/full is NOT available")
+	}
+	var errs []error
+	wt, err := file.Open(file.Config{
+		Path:         "/dev",
+		Filename:     "full",
+		MinFreeSpace: 1 << 62,
+		ErrorHandler: func(_ []byte, _ *iface.Record, err error) {
+			errs = append(errs, err)
+		},
+	})
+	if err != nil {
+		t.Fatalf("TestSpaceFull: %v", err)
+	}
+	defer wt.Close()
+
+	for i := 0; i < 5; i++ {
+		wt.Write([]byte("error\n"), &iface.Record{Time: time.Now(), Level: iface.Error})
+	}
+	// the low space and the first error of writing
+	if len(errs) != 2 {
+		t.Fatalf("TestSpaceFull: errors: %v", errs)
+	}
+	if _, ok := errs[0].(*file.LowSpaceError); !ok {
+		t.Errorf("TestSpaceFull: low: %v", errs[0])
+	}
+}
+
+func TestSpaceRetention(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	createFiles(t, dir, time.Now(),
+		"app.20200101.000000.000000.log",
+		"app.20200102.000000.000000.log",
+		"app.20200103.000000.000000.log",
+		"other.log")
+	var errs []error
+	wt, err := file.Open(file.Config{
+		Path:         dir,
+		Base:         "app",
+		NoDirForDays: true,
+		MaxDirUsage:  10,
+		SpacePolicy:  file.SpaceRetention,
+		ErrorHandler: func(_ []byte, _ *iface.Record, err error) {
+			errs = append(errs, err)
+		},
+	})
+	if err != nil {
+		t.Fatalf("TestSpaceRetention: %v", err)
+	}
+	wt.Write([]byte("log\n"), &iface.Record{Time: time.Now()})
+	wt.Close()
+
+	names := listNames(t, dir)
+	if len(errs) != 0 || len(names) != 3 ||
+		names[0] != "app.20200103.000000.000000.log" ||
+		names[2] != "other.log" {
+		t.Errorf("TestSpaceRetention: %v, %v", names, errs)
+	}
+}
+
+func encodePEM(t *testing.T, typ string, key interface{}) string {
+	var der []byte
+	var err error
+	if typ == "PUBLIC KEY" {
+		der, err = x509.MarshalPKIXPublicKey(key)
+	} else {
+		der, err = x509.MarshalPKCS8PrivateKey(key)
+	}
+	if err != nil {
+		t.Fatalf("%s: %v", t.Name(), err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}))
+}
+
+func readGCM(t *testing.T, data []byte, config file.ReaderConfig) (string, error) {
+	reader, err := file.NewReader(bytes.NewReader(data), config)
+	if err != nil {
+		t.Fatalf("%s: %v", t.Name(), err)
+	}
+	bs, err := ioutil.ReadAll(reader)
+	return string(bs), err
+}
+
+func readFiles(t *testing.T, pathnames []string, config file.ReaderConfig) string {
+	var content []byte
+	for _, pathname := range pathnames {
+		reader, err := file.OpenReader(pathname, config)
+		if err != nil {
+			t.Fatalf("%s: %v", t.Name(), err)
+		}
+		bs, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatalf("%s: %s: %v", t.Name(), pathname, err)
+		}
+		content = append(content, bs...)
+	}
+	return string(content)
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "gxlog-file")
+	if err != nil {
+		t.Fatalf("%s: %v", t.Name(), err)
+	}
+	return dir
+}
+
+func createFiles(t *testing.T, dir string, modTime time.Time, names ...string) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatalf("%s: %v", t.Name(), err)
+	}
+	for _, name := range names {
+		pathname := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(pathname, []byte("log\n"), 0600); err != nil {
+			t.Fatalf("%s: %v", t.Name(), err)
+		}
+		os.Chtimes(pathname, modTime, modTime)
+	}
+}
+
+func listNames(t *testing.T, dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("%s: %v", t.Name(), err)
+	}
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func equalStrings(left, right []string) bool {
+	if len(left) != len(right) {
+		return false
+	}
+	for i := range left {
+		if left[i] != right[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,59 @@
+//go:build unix
+
+package file_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gxlog/gxlog/iface"
+	"github.com/gxlog/gxlog/writer/file"
+)
+
+func TestReopenOnSignal(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	wt, err := file.Open(file.Config{
+		Path:           dir,
+		Filename:       "app.log",
+		ReopenOnSignal: true,
+		CheckInterval:  time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("TestReopenOnSignal: %v", err)
+	}
+	defer wt.Close()
+	pathname := filepath.Join(dir, "app.log")
+	write := func(log string) {
+		wt.Write([]byte(log), &iface.Record{Time: time.Now()})
+	}
+
+	write("log 0\n")
+	file.HandleSignals(syscall.SIGUSR1)
+	defer file.StopSignals()
+	os.Rename(pathname, pathname+".1")
+	syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) {
+		write("log 1\n")
+		if _, err := os.Stat(pathname); err == nil {
+			break
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	bs, _ := ioutil.ReadFile(pathname + ".1")
+	if !strings.HasPrefix(string(bs), "log 0\n") ||
+		strings.Trim(strings.TrimPrefix(string(bs), "log 0\n"), "log 1\n") != "" {
+		t.Errorf("TestReopenOnSignal: renamed: %q", bs)
+	}
+	if bs, _ := ioutil.ReadFile(pathname); string(bs) != "log 1\n" {
+		t.Errorf("TestReopenOnSignal: reopened: %q", bs)
+	}
+}